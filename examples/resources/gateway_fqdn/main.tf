@@ -14,7 +14,7 @@ resource "grid_fqdn_proxy" "p1" {
   node = 40
   name = "workloadname"
   fqdn = "remote.omar.grid.tf"
-  backends = [format("https://137.184.106.152:443")]
+  backends = ["137.184.106.152:443"]
   tls_passthrough = true
 }
 
@@ -0,0 +1,32 @@
+terraform {
+  required_providers {
+    grid = {
+      source = "threefoldtech/grid"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+variable "tsig_secret" {
+  type      = string
+  sensitive = true
+}
+
+resource "grid_fqdn_proxy" "p1" {
+  name     = "workloadname"
+  fqdn     = "app.example.com"
+  backends = ["http://137.184.106.152:8080"]
+  dns {
+    server         = "ns1.example.com:53"
+    zone           = "example.com"
+    tsig_key_name  = "update-key"
+    tsig_algorithm = "hmac-sha256"
+    tsig_secret    = var.tsig_secret
+  }
+}
+
+output "node" {
+  value = grid_fqdn_proxy.p1.node
+}
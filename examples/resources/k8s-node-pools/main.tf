@@ -0,0 +1,65 @@
+terraform {
+  required_providers {
+    grid = {
+      source = "threefoldtech/grid"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "grid_scheduler" "sched" {
+  requests {
+    name = "node1"
+    cru  = 2
+    sru  = 20 * 1024
+    mru  = 4096
+  }
+  requests {
+    name = "node2"
+    cru  = 2
+    sru  = 20 * 1024
+    mru  = 4096
+  }
+}
+
+resource "grid_network" "net1" {
+  name          = "k8spools"
+  nodes         = distinct(values(grid_scheduler.sched.nodes))
+  ip_range      = "10.1.0.0/16"
+  description   = "k8s node pools network"
+  add_wg_access = true
+}
+
+resource "grid_kubernetes" "k8s1" {
+  name         = "k8spools"
+  network_name = grid_network.net1.name
+  token        = "12345678910122"
+  ssh_key      = file("~/.ssh/id_rsa.pub")
+
+  master {
+    disk_size = 20
+    node      = grid_scheduler.sched.nodes["node1"]
+    name      = "mr"
+    cpu       = 2
+    publicip  = true
+    memory    = 2048
+  }
+  # workers are picked among the network nodes, scaling the count keeps the existing ones
+  node_pools {
+    name      = "pool"
+    count     = 2
+    disk_size = 15
+    cpu       = 1
+    memory    = 1024
+  }
+}
+
+output "pool_nodes" {
+  value = grid_kubernetes.k8s1.node_pools[0].nodes
+}
+
+output "wg_config" {
+  value = grid_network.net1.access_wg_config
+}
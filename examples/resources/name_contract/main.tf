@@ -0,0 +1,25 @@
+terraform {
+  required_providers {
+    grid = {
+      source = "threefoldtech/grid"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "grid_name_contract" "n1" {
+  name            = "ashraf"
+  keep_on_destroy = true
+}
+
+resource "grid_name_proxy" "p1" {
+  name          = grid_name_contract.n1.name
+  name_contract = grid_name_contract.n1.contract_id
+  backends      = ["http://69.166.231.35:9000"]
+}
+
+output "fqdn" {
+  value = grid_name_proxy.p1.fqdn
+}
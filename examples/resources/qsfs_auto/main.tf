@@ -0,0 +1,56 @@
+terraform {
+  required_providers {
+    grid = {
+      source = "threefoldtech/grid"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "grid_network" "net1" {
+  nodes       = [7]
+  ip_range    = "10.1.0.0/16"
+  name        = "network"
+  description = "newer network"
+}
+
+resource "grid_qsfs" "qsfs" {
+  name           = "qsfs"
+  node           = 7
+  network_name   = grid_network.net1.name
+  zdb_nodes      = [7, 8, 11]
+  meta_shards    = 4
+  data_shards    = 4
+  minimal_shards = 2
+  zdb_size       = 10
+  zdb_password   = "password"
+  cache          = 10240 # 10 GB
+  encryption_key = "4d778ba3216e4da4231540c92a55f06157cabba802f9b68fb0f78375d2e825af"
+  vms {
+    name       = "vm"
+    flist      = "https://hub.grid.tf/tf-official-apps/base:latest.flist"
+    cpu        = 2
+    memory     = 1024
+    entrypoint = "/sbin/zinit init"
+    planetary  = true
+    env_vars = {
+      SSH_KEY = "PUT YOUR SSH KEY HERE"
+    }
+    mounts {
+      disk_name   = "qsfs"
+      mount_point = "/qsfs"
+    }
+  }
+}
+
+output "metrics" {
+  value = grid_qsfs.qsfs.metrics_endpoint
+}
+output "zdbs" {
+  value = grid_qsfs.qsfs.zdbs
+}
+output "ygg_ip" {
+  value = grid_qsfs.qsfs.vms[0].ygg_ip
+}
@@ -0,0 +1,48 @@
+terraform {
+  required_providers {
+    grid = {
+      source = "threefoldtech/grid"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+locals {
+  name = "myvm"
+}
+
+resource "grid_network" "net1" {
+  nodes       = [8]
+  ip_range    = "10.1.0.0/24"
+  name        = local.name
+  description = "newer network"
+}
+resource "grid_deployment" "d1" {
+  name         = local.name
+  node         = 8
+  network_name = grid_network.net1.name
+  vms {
+    name      = "vm1"
+    flist     = "https://hub.grid.tf/tf-official-apps/strm-helloworld-http-latest.flist"
+    cpu       = 2
+    memory    = 1024
+    planetary = true
+  }
+}
+# exposes the vm's port 9000 over its yggdrasil ip through a gateway node picked from freefarm
+resource "grid_vm_gateway" "gw" {
+  name          = "myvm"
+  node          = grid_deployment.d1.node
+  deployment_id = grid_deployment.d1.id
+  vm            = grid_deployment.d1.vms[0].name
+  port          = 9000
+  farm          = "freefarm"
+}
+output "fqdn" {
+  value = grid_vm_gateway.gw.fqdn
+}
+output "gateway_node" {
+  value = grid_vm_gateway.gw.gateway_node
+}
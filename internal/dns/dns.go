@@ -0,0 +1,15 @@
+// Package dns manages the dns records of the domains served by gateways
+package dns
+
+import (
+	"context"
+	"net"
+)
+
+// Provider manages the address records of domain names
+type Provider interface {
+	// SetRecords replaces the A/AAAA records of the name with the given ips
+	SetRecords(ctx context.Context, name string, ips []net.IP) error
+	// DeleteRecords removes the A/AAAA records of the name
+	DeleteRecords(ctx context.Context, name string) error
+}
@@ -0,0 +1,147 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"time"
+
+	mdns "github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	tsigFudge      = 300
+)
+
+// tsigAlgorithms maps the supported tsig algorithms to their names in the messages
+var tsigAlgorithms = map[string]string{
+	"hmac-sha1":   mdns.HmacSHA1,
+	"hmac-sha256": mdns.HmacSHA256,
+	"hmac-sha512": mdns.HmacSHA512,
+}
+
+// RFC2136 manages the records through dynamic updates (rfc 2136) sent to the zone's primary server, e.g. bind or coredns
+type RFC2136 struct {
+	// Server is the host:port of the dns server accepting the updates
+	Server string
+	// Zone is the zone containing the managed names
+	Zone string
+	TTL  uint32
+	// Timeout of each update, defaults to 10 seconds
+	Timeout time.Duration
+
+	keyName   string
+	algorithm string
+	// secret is the base64 encoded tsig secret
+	secret string
+}
+
+// NewRFC2136 creates a dynamic updates provider, the updates are signed with tsig if a key name is given
+// and the responses must be signed with the same key
+func NewRFC2136(server, zone string, ttl uint32, keyName, algorithm, secret string) (*RFC2136, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return nil, errors.Wrapf(err, "dns server %s must be in the format host:port", server)
+	}
+	if zone == "" {
+		return nil, errors.New("dns zone can't be empty")
+	}
+	p := &RFC2136{
+		Server:  server,
+		Zone:    mdns.CanonicalName(zone),
+		TTL:     ttl,
+		Timeout: defaultTimeout,
+	}
+	if keyName == "" {
+		return p, nil
+	}
+	if _, ok := tsigAlgorithms[algorithm]; !ok {
+		return nil, fmt.Errorf("unsupported tsig algorithm %s", algorithm)
+	}
+	if _, err := base64.StdEncoding.DecodeString(secret); err != nil {
+		return nil, errors.Wrap(err, "tsig secret must be base64 encoded")
+	}
+	p.keyName = mdns.CanonicalName(keyName)
+	p.algorithm = tsigAlgorithms[algorithm]
+	p.secret = secret
+	return p, nil
+}
+
+// SetRecords replaces the A/AAAA records of the name with the given ips in a single update
+func (p *RFC2136) SetRecords(ctx context.Context, name string, ips []net.IP) error {
+	if len(ips) == 0 {
+		return fmt.Errorf("no ips to point %s to", name)
+	}
+	name = mdns.Fqdn(name)
+	records := make([]mdns.RR, 0, len(ips))
+	for _, ip := range ips {
+		if ip == nil {
+			return fmt.Errorf("invalid ip for %s", name)
+		}
+		records = append(records, addressRecord(name, ip, p.TTL))
+	}
+	m := p.newUpdate(name)
+	m.Insert(records)
+	return errors.Wrapf(p.update(ctx, name, m), "couldn't set the records of %s", name)
+}
+
+// DeleteRecords removes the A/AAAA records of the name
+func (p *RFC2136) DeleteRecords(ctx context.Context, name string) error {
+	name = mdns.Fqdn(name)
+	return errors.Wrapf(p.update(ctx, name, p.newUpdate(name)), "couldn't delete the records of %s", name)
+}
+
+// newUpdate returns an update of the zone deleting the A and AAAA rrsets of the name
+func (p *RFC2136) newUpdate(name string) *mdns.Msg {
+	m := new(mdns.Msg)
+	m.SetUpdate(p.Zone)
+	m.RemoveRRset([]mdns.RR{
+		&mdns.A{Hdr: mdns.RR_Header{Name: name, Rrtype: mdns.TypeA}},
+		&mdns.AAAA{Hdr: mdns.RR_Header{Name: name, Rrtype: mdns.TypeAAAA}},
+	})
+	return m
+}
+
+func addressRecord(name string, ip net.IP, ttl uint32) mdns.RR {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &mdns.A{
+			Hdr: mdns.RR_Header{Name: name, Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: ttl},
+			A:   ip4,
+		}
+	}
+	return &mdns.AAAA{
+		Hdr:  mdns.RR_Header{Name: name, Rrtype: mdns.TypeAAAA, Class: mdns.ClassINET, Ttl: ttl},
+		AAAA: ip,
+	}
+}
+
+// update sends the update, the tsig of the response is verified by the client
+func (p *RFC2136) update(ctx context.Context, name string, m *mdns.Msg) error {
+	if !mdns.IsSubDomain(p.Zone, mdns.CanonicalName(name)) {
+		return fmt.Errorf("%s is not in zone %s", name, p.Zone)
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := mdns.Client{Net: "udp"}
+	if p.keyName != "" {
+		m.SetTsig(p.keyName, p.algorithm, tsigFudge, time.Now().Unix())
+		client.TsigSecret = map[string]string{p.keyName: p.secret}
+	}
+	resp, _, err := client.ExchangeContext(ctx, m, p.Server)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't send the update to %s", p.Server)
+	}
+	if resp.Rcode != mdns.RcodeSuccess {
+		return fmt.Errorf("update failed: %s", mdns.RcodeToString[resp.Rcode])
+	}
+	if p.keyName != "" && resp.IsTsig() == nil {
+		return fmt.Errorf("the response of %s isn't signed", p.Server)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	mdns "github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "c2VjcmV0"
+
+// fakeServer answers each update with the given rcode and sends the received messages to the channel.
+// the updates are checked and the responses signed with the secret if it's not empty
+func fakeServer(t *testing.T, rcode int, secret string) (string, <-chan *mdns.Msg) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	received := make(chan *mdns.Msg, 10)
+	server := &mdns.Server{
+		PacketConn: conn,
+		Handler: mdns.HandlerFunc(func(w mdns.ResponseWriter, r *mdns.Msg) {
+			received <- r
+			resp := new(mdns.Msg)
+			resp.SetRcode(r, rcode)
+			if tsig := r.IsTsig(); tsig != nil && secret != "" {
+				if w.TsigStatus() != nil {
+					resp.SetRcode(r, mdns.RcodeNotAuth)
+				}
+				resp.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, int64(tsig.TimeSigned))
+			}
+			assert.NoError(t, w.WriteMsg(resp))
+		}),
+		// the default accept function refuses updates
+		MsgAcceptFunc: func(dh mdns.Header) mdns.MsgAcceptAction { return mdns.MsgAccept },
+	}
+	if secret != "" {
+		server.TsigSecret = map[string]string{"update-key.": secret}
+	}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return conn.LocalAddr().String(), received
+}
+
+func TestRFC2136SetRecords(t *testing.T) {
+	server, received := fakeServer(t, mdns.RcodeSuccess, testSecret)
+	p, err := NewRFC2136(server, "example.com", 300, "update-key", "hmac-sha256", testSecret)
+	assert.NoError(t, err)
+
+	err = p.SetRecords(context.Background(), "gw.example.com", []net.IP{net.ParseIP("185.206.122.33"), net.ParseIP("2a10:b600:1::cc4")})
+	assert.NoError(t, err)
+	msg := <-received
+	assert.Equal(t, mdns.OpcodeUpdate, msg.Opcode)
+	assert.Equal(t, "example.com.", msg.Question[0].Name)
+	assert.NotNil(t, msg.IsTsig())
+	// two deletions of the A and AAAA rrsets then the new records
+	assert.Len(t, msg.Ns, 4)
+	assert.Equal(t, uint16(mdns.ClassANY), msg.Ns[0].Header().Class)
+	assert.Equal(t, mdns.TypeA, msg.Ns[0].Header().Rrtype)
+	assert.Equal(t, mdns.TypeAAAA, msg.Ns[1].Header().Rrtype)
+	assert.Equal(t, "185.206.122.33", msg.Ns[2].(*mdns.A).A.String())
+	assert.Equal(t, uint32(300), msg.Ns[2].Header().Ttl)
+	assert.Equal(t, "2a10:b600:1::cc4", msg.Ns[3].(*mdns.AAAA).AAAA.String())
+
+	err = p.DeleteRecords(context.Background(), "gw.example.com")
+	assert.NoError(t, err)
+	msg = <-received
+	assert.Len(t, msg.Ns, 2)
+}
+
+func TestRFC2136TSIG(t *testing.T) {
+	// the server doesn't accept the key
+	server, _ := fakeServer(t, mdns.RcodeSuccess, "b3RoZXI=")
+	p, err := NewRFC2136(server, "example.com", 300, "update-key", "hmac-sha256", testSecret)
+	assert.NoError(t, err)
+	err = p.SetRecords(context.Background(), "gw.example.com", []net.IP{net.ParseIP("185.206.122.33")})
+	assert.Error(t, err)
+
+	// the response isn't signed
+	server, _ = fakeServer(t, mdns.RcodeSuccess, "")
+	p, err = NewRFC2136(server, "example.com", 300, "update-key", "hmac-sha256", testSecret)
+	assert.NoError(t, err)
+	err = p.SetRecords(context.Background(), "gw.example.com", []net.IP{net.ParseIP("185.206.122.33")})
+	assert.ErrorContains(t, err, "isn't signed")
+}
+
+func TestRFC2136Errors(t *testing.T) {
+	server, _ := fakeServer(t, mdns.RcodeRefused, "")
+	p, err := NewRFC2136(server, "example.com", 300, "", "", "")
+	assert.NoError(t, err)
+
+	err = p.SetRecords(context.Background(), "gw.example.com", []net.IP{net.ParseIP("185.206.122.33")})
+	assert.ErrorContains(t, err, "REFUSED")
+
+	err = p.SetRecords(context.Background(), "gw.example.org", []net.IP{net.ParseIP("185.206.122.33")})
+	assert.ErrorContains(t, err, "not in zone")
+
+	err = p.SetRecords(context.Background(), "gwexample.com", []net.IP{net.ParseIP("185.206.122.33")})
+	assert.ErrorContains(t, err, "not in zone")
+
+	err = p.SetRecords(context.Background(), "gw.example.com", nil)
+	assert.Error(t, err)
+
+	_, err = NewRFC2136("127.0.0.1", "example.com", 300, "", "", "")
+	assert.Error(t, err)
+	_, err = NewRFC2136(server, "example.com", 300, "update-key", "hmac-md5", testSecret)
+	assert.Error(t, err)
+	_, err = NewRFC2136(server, "example.com", 300, "update-key", "hmac-sha256", "not base64")
+	assert.Error(t, err)
+}
@@ -4,19 +4,30 @@ package provider
 import (
 	"context"
 	"fmt"
+	"log"
+	"net"
 	"strconv"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
+	proxy "github.com/threefoldtech/grid_proxy_server/pkg/client"
 	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
 )
 
+// GatewayPublicConfig is the part of the gateway node public config needed to reach its proxies
+type GatewayPublicConfig struct {
+	Domain string
+	IPv4   string
+	IPv6   string
+}
+
 func dataSourceGatewayDomain() *schema.Resource {
 	return &schema.Resource{
 		// This description is used by the documentation generator and the language server.
-		Description: "Data source for computing gateway name proxy fqdn.",
+		Description: "Data source for computing gateway name proxy fqdn and the public ips of the gateway node.",
 
 		ReadContext: dataSourceGatewayRead,
 
@@ -36,11 +47,20 @@ func dataSourceGatewayDomain() *schema.Resource {
 				Computed:    true,
 				Description: "Fullly qualified domain name",
 			},
+			"ipv4": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Public IPv4 of the gateway node, the A record of an fqdn proxy domain should point to it",
+			},
+			"ipv6": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Public IPv6 of the gateway node, the AAAA record of an fqdn proxy domain should point to it",
+			},
 		},
 	}
 }
 
-// TODO: make this non failing
 func dataSourceGatewayRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient, ok := meta.(*apiClient)
 	if !ok {
@@ -50,25 +70,74 @@ func dataSourceGatewayRead(ctx context.Context, d *schema.ResourceData, meta int
 	nodeID := uint32(d.Get("node").(int))
 	name := d.Get("name").(string)
 	ncPool := client.NewNodeClientPool(apiClient.rmb)
-	nodeClient, err := ncPool.GetNodeClient(apiClient.substrateConn, nodeID)
-	if err != nil {
-		return diag.FromErr(errors.Wrap(err, "failed to get node client"))
-	}
-	ctx2, cancel := context.WithTimeout(ctx, time.Minute)
-	defer cancel()
-	cfg, err := nodeClient.NetworkGetPublicConfig(ctx2)
+	cfg, err := gatewayPublicConfig(ctx, apiClient.substrateConn, ncPool, apiClient.grid_client, nodeID)
 	if err != nil {
-		return diag.FromErr(errors.Wrap(err, "couldn't get node public config"))
+		return diag.FromErr(err)
 	}
 	if cfg.Domain == "" {
-		return diag.FromErr(errors.New("node doesn't contain a domain in its public config"))
+		return diag.FromErr(fmt.Errorf("node %d doesn't contain a domain in its public config", nodeID))
 	}
 	fqdn := fmt.Sprintf("%s.%s", name, cfg.Domain)
 	err = d.Set("fqdn", fqdn)
 	if err != nil {
 		return diag.FromErr(errors.Wrap(err, "couldn't set fqdn"))
 	}
+	err = d.Set("ipv4", cfg.IPv4)
+	if err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set ipv4"))
+	}
+	err = d.Set("ipv6", cfg.IPv6)
+	if err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set ipv6"))
+	}
 
 	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
 	return nil
 }
+
+// gatewayPublicConfig gets the public config from the node itself, falling back to the one reported by the grid proxy if the node can't be reached
+func gatewayPublicConfig(ctx context.Context, sub subi.SubstrateExt, ncPool client.NodeClientGetter, gridClient proxy.Client, nodeID uint32) (GatewayPublicConfig, error) {
+	cfg, err := nodePublicConfig(ctx, sub, ncPool, nodeID)
+	if err == nil {
+		return cfg, nil
+	}
+	log.Printf("couldn't get node %d public config from the node, falling back to the grid proxy: %s", nodeID, err)
+	node, proxyErr := gridClient.Node(nodeID)
+	if proxyErr != nil {
+		return GatewayPublicConfig{}, errors.Wrapf(proxyErr, "couldn't get node %d public config from the node (%s) nor the grid proxy", nodeID, err)
+	}
+	return GatewayPublicConfig{
+		Domain: node.PublicConfig.Domain,
+		IPv4:   stripIPMask(node.PublicConfig.Ipv4),
+		IPv6:   stripIPMask(node.PublicConfig.Ipv6),
+	}, nil
+}
+
+func nodePublicConfig(ctx context.Context, sub subi.SubstrateExt, ncPool client.NodeClientGetter, nodeID uint32) (GatewayPublicConfig, error) {
+	nodeClient, err := ncPool.GetNodeClient(sub, nodeID)
+	if err != nil {
+		return GatewayPublicConfig{}, errors.Wrap(err, "failed to get node client")
+	}
+	ctx2, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	cfg, err := nodeClient.NetworkGetPublicConfig(ctx2)
+	if err != nil {
+		return GatewayPublicConfig{}, errors.Wrap(err, "couldn't get node public config")
+	}
+	res := GatewayPublicConfig{Domain: cfg.Domain}
+	if cfg.IPv4.IP != nil {
+		res.IPv4 = cfg.IPv4.IP.String()
+	}
+	if cfg.IPv6.IP != nil {
+		res.IPv6 = cfg.IPv6.IP.String()
+	}
+	return res, nil
+}
+
+// stripIPMask returns the ip of an address in cidr notation
+func stripIPMask(ip string) string {
+	if parsed, _, err := net.ParseCIDR(ip); err == nil {
+		return parsed.String()
+	}
+	return ip
+}
@@ -0,0 +1,66 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	proxyTypes "github.com/threefoldtech/grid_proxy_server/pkg/types"
+	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
+	mock "github.com/threefoldtech/terraform-provider-grid/internal/provider/mocks"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+func TestGatewayPublicConfig(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sub := mock.NewMockSubstrateExt(ctrl)
+	cl := mock.NewRMBMockClient(ctrl)
+	pool := mock.NewMockNodeClientGetter(ctrl)
+	gridClient := mock.NewMockClient(ctrl)
+
+	pool.EXPECT().
+		GetNodeClient(sub, uint32(7)).
+		Return(client.NewNodeClient(12, cl), nil).
+		Times(2)
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.network.public_config_get", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, twin uint32, fn string, data, result interface{}) error {
+			_, ipv4, _ := net.ParseCIDR("185.206.122.33/24")
+			ipv4.IP = net.ParseIP("185.206.122.33")
+			*result.(*client.PublicConfig) = client.PublicConfig{
+				IPv4:   gridtypes.NewIPNet(*ipv4),
+				Domain: "gent01.grid.tf",
+			}
+			return nil
+		})
+	cfg, err := gatewayPublicConfig(context.Background(), sub, pool, gridClient, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, GatewayPublicConfig{Domain: "gent01.grid.tf", IPv4: "185.206.122.33"}, cfg)
+
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.network.public_config_get", gomock.Any(), gomock.Any()).
+		Return(errors.New("timeout"))
+	gridClient.EXPECT().Node(uint32(7)).Return(proxyTypes.NodeWithNestedCapacity{
+		PublicConfig: proxyTypes.PublicConfig{
+			Domain: "gent01.grid.tf",
+			Ipv4:   "185.206.122.33/24",
+			Ipv6:   "2a10:b600:1::cc4/64",
+		},
+	}, nil)
+	cfg, err = gatewayPublicConfig(context.Background(), sub, pool, gridClient, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, GatewayPublicConfig{Domain: "gent01.grid.tf", IPv4: "185.206.122.33", IPv6: "2a10:b600:1::cc4"}, cfg)
+
+	pool.EXPECT().
+		GetNodeClient(sub, uint32(8)).
+		Return(nil, errors.New("node not found"))
+	gridClient.EXPECT().Node(uint32(8)).Return(proxyTypes.NodeWithNestedCapacity{}, errors.New("not found"))
+	_, err = gatewayPublicConfig(context.Background(), sub, pool, gridClient, 8)
+	assert.Error(t, err)
+}
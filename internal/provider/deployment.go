@@ -15,6 +15,7 @@ import (
 	"github.com/pkg/errors"
 	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
 	"github.com/threefoldtech/terraform-provider-grid/pkg/deployer"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/state"
 	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
 	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
 	"github.com/threefoldtech/zos/pkg/gridtypes"
@@ -116,26 +117,20 @@ func (d *DeploymentDeployer) assignNodesIPs() error {
 		return errors.Wrapf(err, "invalid ip %s", d.IPRange)
 	}
 	for _, vm := range d.VMs {
-		if vm.IP != "" && cidr.Contains(net.ParseIP(vm.IP)) && !Contains(usedIPs, net.ParseIP(vm.IP)[3]) {
-			usedIPs = append(usedIPs, net.ParseIP(vm.IP)[3])
+		if vm.IP != "" && cidr.Contains(net.ParseIP(vm.IP)) && !Contains(usedIPs, state.HostID(*cidr, net.ParseIP(vm.IP))) {
+			usedIPs = append(usedIPs, state.HostID(*cidr, net.ParseIP(vm.IP)))
 		}
 	}
-	cur := byte(2)
 	for idx, vm := range d.VMs {
 		if vm.IP != "" && cidr.Contains(net.ParseIP(vm.IP)) {
 			continue
 		}
-		ip := cidr.IP
-		ip[3] = cur
-		for Contains(usedIPs, ip[3]) {
-			if cur == 254 {
-				return errors.New("all 253 ips of the network are exhausted")
-			}
-			cur++
-			ip[3] = cur
+		hostID, err := state.NextFreeHostID(*cidr, usedIPs)
+		if err != nil {
+			return err
 		}
-		d.VMs[idx].IP = ip.String()
-		usedIPs = append(usedIPs, ip[3])
+		d.VMs[idx].IP = state.HostIP(*cidr, hostID).String()
+		usedIPs = append(usedIPs, hostID)
 	}
 	return nil
 }
@@ -181,7 +176,7 @@ func (d *DeploymentDeployer) Marshal(r *schema.ResourceData) (errors error) {
 		zdbs = append(zdbs, zdb.Dictify())
 	}
 	for _, q := range d.QSFSs {
-		qsfs = append(zdbs, q.Dictify())
+		qsfs = append(qsfs, q.Dictify())
 	}
 	err := r.Set("vms", vms)
 	if err != nil {
@@ -286,7 +281,11 @@ func (d *DeploymentDeployer) sync(ctx context.Context, sub subi.SubstrateExt, cl
 	network := ns.GetNetwork(d.NetworkName)
 	network.DeleteDeployment(d.Node, d.Id)
 
-	usedIPs := []byte{}
+	_, cidr, err := net.ParseCIDR(d.IPRange)
+	if err != nil {
+		log.Printf("couldn't parse node ip range %s, vms ips won't be marked as used: %s", d.IPRange, err.Error())
+	}
+	usedIPs := []uint32{}
 	for _, w := range dl.Workloads {
 		if !w.Result.State.IsOkay() {
 			continue
@@ -300,8 +299,9 @@ func (d *DeploymentDeployer) sync(ctx context.Context, sub subi.SubstrateExt, cl
 			}
 			vms = append(vms, vm)
 
-			ip := net.ParseIP(vm.IP).To4()
-			usedIPs = append(usedIPs, ip[3])
+			if cidr != nil {
+				usedIPs = append(usedIPs, state.HostID(*cidr, net.ParseIP(vm.IP)))
+			}
 		case zos.ZDBType:
 			zdb, err := workloads.NewZDBFromWorkload(&w)
 			if err != nil {
@@ -315,6 +315,12 @@ func (d *DeploymentDeployer) sync(ctx context.Context, sub subi.SubstrateExt, cl
 				log.Printf("error parsing qsfs: %s", err.Error())
 				continue
 			}
+			if q.MetricsEndpoint != "" {
+				q.BackendsStatus, err = workloads.GetBackendsStatus(q.MetricsEndpoint)
+				if err != nil {
+					log.Printf("couldn't get qsfs %s backends status: %s", q.Name, err.Error())
+				}
+			}
 			qsfs = append(qsfs, q)
 		case zos.ZMountType:
 			disk, err := workloads.NewDiskFromWorkload(&w)
@@ -12,6 +12,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	mock "github.com/threefoldtech/terraform-provider-grid/internal/provider/mocks"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/state"
 	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
 	"github.com/threefoldtech/zos/pkg/gridtypes"
 	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
@@ -291,7 +292,7 @@ func TestDeploymentGenerateDeployment(t *testing.T) {
 	state.EXPECT().GetNetworkState().Return(netState)
 	network := mock.NewMockNetwork(ctrl)
 	netState.EXPECT().GetNetwork(d.NetworkName).Return(network)
-	network.EXPECT().GetNodeIPsList(d.Node).Return([]byte{})
+	network.EXPECT().GetNodeIPsList(d.Node).Return([]uint32{})
 	dl, err := d.GenerateVersionlessDeployments(context.Background())
 	assert.NoError(t, err)
 	var wls []gridtypes.Workload
@@ -327,7 +328,7 @@ func TestDeploymentSync(t *testing.T) {
 	state.EXPECT().GetNetworkState().AnyTimes().Return(netState)
 	network := mock.NewMockNetwork(ctrl)
 	netState.EXPECT().GetNetwork(d.NetworkName).AnyTimes().Return(network)
-	network.EXPECT().GetNodeIPsList(d.Node).Return([]byte{})
+	network.EXPECT().GetNodeIPsList(d.Node).Return([]uint32{})
 	dls, err := d.GenerateVersionlessDeployments(context.Background())
 	assert.NoError(t, err)
 	dl := dls[d.Node]
@@ -425,7 +426,7 @@ func TestDeploymentSync(t *testing.T) {
 	var cp DeploymentDeployer
 	musUnmarshal(mustMarshal(d), &cp)
 	network.EXPECT().DeleteDeployment(d.Node, d.Id)
-	usedIPs := getUsedIPs(dl)
+	usedIPs := getUsedIPs(dl, d.IPRange)
 	network.EXPECT().SetDeploymentIPs(d.Node, d.Id, usedIPs)
 	assert.NoError(t, d.sync(context.Background(), sub, d.APIClient))
 	assert.Equal(t, d.VMs, cp.VMs)
@@ -436,8 +437,9 @@ func TestDeploymentSync(t *testing.T) {
 	assert.Equal(t, d.Node, cp.Node)
 }
 
-func getUsedIPs(dl gridtypes.Deployment) []byte {
-	usedIPs := []byte{}
+func getUsedIPs(dl gridtypes.Deployment, ipRange string) []uint32 {
+	_, cidr, _ := net.ParseCIDR(ipRange)
+	usedIPs := []uint32{}
 	for _, w := range dl.Workloads {
 		if !w.Result.State.IsOkay() {
 			continue
@@ -449,8 +451,7 @@ func getUsedIPs(dl gridtypes.Deployment) []byte {
 				continue
 			}
 
-			ip := net.ParseIP(vm.IP).To4()
-			usedIPs = append(usedIPs, ip[3])
+			usedIPs = append(usedIPs, state.HostID(*cidr, net.ParseIP(vm.IP)))
 		}
 	}
 	return usedIPs
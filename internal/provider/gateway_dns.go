@@ -0,0 +1,106 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/terraform-provider-grid/internal/dns"
+	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+)
+
+const dnsProviderRFC2136 = "rfc2136"
+
+// GatewayDNS points the domain of an fqdn proxy to its gateway node
+type GatewayDNS struct {
+	Provider dns.Provider
+	// OldFQDN is the previous domain of the proxy whose records are removed once the new one is deployed
+	OldFQDN string
+	// TTL of the records, resolvers may keep pointing to the old node for this long after the records change
+	TTL time.Duration
+}
+
+// NewGatewayDNS loads the dns configuration of the resource, it returns nil if the records aren't managed by the provider
+func NewGatewayDNS(d *schema.ResourceData) (*GatewayDNS, error) {
+	dnsIf := d.Get("dns").([]interface{})
+	if len(dnsIf) == 0 || dnsIf[0] == nil {
+		return nil, nil
+	}
+	m := dnsIf[0].(map[string]interface{})
+	provider, err := newDNSProvider(m)
+	if err != nil {
+		return nil, err
+	}
+	g := GatewayDNS{
+		Provider: provider,
+		TTL:      time.Duration(m["ttl"].(int)) * time.Second,
+	}
+	if d.HasChange("fqdn") {
+		old, _ := d.GetChange("fqdn")
+		g.OldFQDN = old.(string)
+	}
+	return &g, nil
+}
+
+func newDNSProvider(m map[string]interface{}) (dns.Provider, error) {
+	switch m["provider"].(string) {
+	case dnsProviderRFC2136:
+		return dns.NewRFC2136(
+			m["server"].(string),
+			m["zone"].(string),
+			uint32(m["ttl"].(int)),
+			m["tsig_key_name"].(string),
+			m["tsig_algorithm"].(string),
+			m["tsig_secret"].(string),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported dns provider %s, supported providers are: %s", m["provider"], dnsProviderRFC2136)
+	}
+}
+
+// pointTo replaces the A/AAAA records of the fqdn with the public ips of the gateway node
+func (g *GatewayDNS) pointTo(ctx context.Context, sub subi.SubstrateExt, apiClient *apiClient, ncPool client.NodeClientGetter, node uint32, fqdn string) error {
+	cfg, err := gatewayPublicConfig(ctx, sub, ncPool, apiClient.grid_client, node)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't get the public ips of gateway node %d", node)
+	}
+	ips := make([]net.IP, 0)
+	for _, ip := range []string{cfg.IPv4, cfg.IPv6} {
+		if parsed := net.ParseIP(ip); parsed != nil {
+			ips = append(ips, parsed)
+		}
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("gateway node %d has no public ips to point %s to", node, fqdn)
+	}
+	return g.Provider.SetRecords(ctx, fqdn, ips)
+}
+
+// waitTTL waits for the records cached by the resolvers before the last change to expire
+func (g *GatewayDNS) waitTTL(ctx context.Context) error {
+	timer := time.NewTimer(g.TTL)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// removeOld removes the records of the previous domain if it was changed
+func (g *GatewayDNS) removeOld(ctx context.Context, fqdn string) error {
+	if g.OldFQDN == "" || g.OldFQDN == fqdn {
+		return nil
+	}
+	if err := g.Provider.DeleteRecords(ctx, g.OldFQDN); err != nil {
+		return err
+	}
+	g.OldFQDN = ""
+	return nil
+}
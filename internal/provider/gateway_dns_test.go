@@ -0,0 +1,80 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	proxyTypes "github.com/threefoldtech/grid_proxy_server/pkg/types"
+	"github.com/threefoldtech/terraform-provider-grid/internal/dns"
+	mock "github.com/threefoldtech/terraform-provider-grid/internal/provider/mocks"
+)
+
+// fakeDNSProvider keeps the records in memory
+type fakeDNSProvider struct {
+	records map[string][]net.IP
+}
+
+func (f *fakeDNSProvider) SetRecords(ctx context.Context, name string, ips []net.IP) error {
+	f.records[name] = ips
+	return nil
+}
+
+func (f *fakeDNSProvider) DeleteRecords(ctx context.Context, name string) error {
+	delete(f.records, name)
+	return nil
+}
+
+func TestNewDNSProvider(t *testing.T) {
+	provider, err := newDNSProvider(map[string]interface{}{
+		"provider":       "rfc2136",
+		"server":         "127.0.0.1:53",
+		"zone":           "example.com",
+		"ttl":            300,
+		"tsig_key_name":  "update-key",
+		"tsig_algorithm": "hmac-sha256",
+		"tsig_secret":    "c2VjcmV0",
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &dns.RFC2136{}, provider)
+
+	_, err = newDNSProvider(map[string]interface{}{"provider": "route53"})
+	assert.Error(t, err)
+}
+
+func TestGatewayDNS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sub := mock.NewMockSubstrateExt(ctrl)
+	pool := mock.NewMockNodeClientGetter(ctrl)
+	gridClient := mock.NewMockClient(ctrl)
+	apiClient := &apiClient{grid_client: gridClient}
+	provider := &fakeDNSProvider{records: map[string][]net.IP{"old.example.com": {net.ParseIP("1.1.1.1")}}}
+	g := GatewayDNS{Provider: provider, OldFQDN: "old.example.com"}
+
+	pool.EXPECT().GetNodeClient(sub, uint32(7)).Return(nil, errors.New("unreachable"))
+	gridClient.EXPECT().Node(uint32(7)).Return(proxyTypes.NodeWithNestedCapacity{
+		PublicConfig: proxyTypes.PublicConfig{
+			Domain: "gent01.grid.tf",
+			Ipv4:   "185.206.122.33/24",
+			Ipv6:   "2a10:b600:1::cc4/64",
+		},
+	}, nil)
+	err := g.pointTo(context.Background(), sub, apiClient, pool, 7, "gw.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("185.206.122.33"), net.ParseIP("2a10:b600:1::cc4")}, provider.records["gw.example.com"])
+
+	assert.NoError(t, g.removeOld(context.Background(), "gw.example.com"))
+	assert.NotContains(t, provider.records, "old.example.com")
+	assert.Contains(t, provider.records, "gw.example.com")
+
+	pool.EXPECT().GetNodeClient(sub, uint32(8)).Return(nil, errors.New("unreachable"))
+	gridClient.EXPECT().Node(uint32(8)).Return(proxyTypes.NodeWithNestedCapacity{}, nil)
+	err = g.pointTo(context.Background(), sub, apiClient, pool, 8, "gw.example.com")
+	assert.Error(t, err)
+}
@@ -25,6 +25,8 @@ type GatewayFQDNDeployer struct {
 	Description      string
 	Node             uint32
 	NodeDeploymentID map[uint32]uint64
+	NodeSelection    GatewayNodeSelection
+	DNS              *GatewayDNS
 
 	APIClient *apiClient
 	ncPool    client.NodeClientGetter
@@ -37,9 +39,10 @@ func NewGatewayFQDNDeployer(ctx context.Context, d *schema.ResourceData, apiClie
 	for idx, n := range backendsIf {
 		backends[idx] = zos.Backend(n.(string))
 	}
-	nodeDeploymentIDIf := d.Get("node_deployment_id").(map[string]interface{})
+	// the old value still holds the deployments planned to be canceled
+	nodeDeploymentIDIf, _ := d.GetChange("node_deployment_id")
 	nodeDeploymentID := make(map[uint32]uint64)
-	for node, id := range nodeDeploymentIDIf {
+	for node, id := range nodeDeploymentIDIf.(map[string]interface{}) {
 		nodeInt, err := strconv.ParseUint(node, 10, 32)
 		if err != nil {
 			return GatewayFQDNDeployer{}, errors.Wrap(err, "couldn't parse node id")
@@ -57,6 +60,10 @@ func NewGatewayFQDNDeployer(ctx context.Context, d *schema.ResourceData, apiClie
 	if err != nil {
 		log.Printf("error parsing deploymentdata: %s", err.Error())
 	}
+	dns, err := NewGatewayDNS(d)
+	if err != nil {
+		return GatewayFQDNDeployer{}, errors.Wrap(err, "couldn't load dns configuration")
+	}
 	deployer := GatewayFQDNDeployer{
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           d.Get("name").(string),
@@ -68,6 +75,8 @@ func NewGatewayFQDNDeployer(ctx context.Context, d *schema.ResourceData, apiClie
 		Description:      d.Get("description").(string),
 		Node:             uint32(d.Get("node").(int)),
 		NodeDeploymentID: nodeDeploymentID,
+		NodeSelection:    NewGatewayNodeSelection(d),
+		DNS:              dns,
 		APIClient:        apiClient,
 		ncPool:           ncPool,
 		deployer:         deployer.NewDeployer(apiClient.identity, apiClient.twin_id, apiClient.grid_client, ncPool, true, nil, string(deploymentDataStr)),
@@ -76,6 +85,9 @@ func NewGatewayFQDNDeployer(ctx context.Context, d *schema.ResourceData, apiClie
 }
 
 func (k *GatewayFQDNDeployer) Validate(ctx context.Context, sub subi.SubstrateExt) error {
+	if err := k.Gw.Validate(); err != nil {
+		return err
+	}
 	return client.AreNodesUp(ctx, sub, []uint32{k.Node}, k.ncPool)
 }
 
@@ -125,6 +137,13 @@ func (k *GatewayFQDNDeployer) GenerateVersionlessDeployments(ctx context.Context
 }
 
 func (k *GatewayFQDNDeployer) Deploy(ctx context.Context, sub subi.SubstrateExt) error {
+	if k.NodeSelection.Auto {
+		node, err := k.NodeSelection.ensureNode(ctx, sub, k.APIClient, k.ncPool, k.Node)
+		if err != nil {
+			return errors.Wrap(err, "couldn't pick a gateway node")
+		}
+		k.Node = node
+	}
 	if err := k.Validate(ctx, sub); err != nil {
 		return err
 	}
@@ -132,10 +151,32 @@ func (k *GatewayFQDNDeployer) Deploy(ctx context.Context, sub subi.SubstrateExt)
 	if err != nil {
 		return errors.Wrap(err, "couldn't generate deployments data")
 	}
+	migrating := gatewayMigrating(k.NodeDeploymentID, k.Node)
+	if migrating {
+		k.NodeDeploymentID, err = migrateGateway(ctx, sub, k.deployer, k.NodeDeploymentID, k.Node, newDeployments[k.Node])
+		if err != nil {
+			return err
+		}
+	}
+	// the records point to the new node once it's ready in case of migration
+	if k.DNS != nil {
+		if err := k.DNS.pointTo(ctx, sub, k.APIClient, k.ncPool, k.Node, k.Gw.FQDN); err != nil {
+			return errors.Wrap(err, "couldn't create the dns records of the domain")
+		}
+		// the old gateway keeps serving the clients resolving the cached records until they expire
+		if migrating && len(k.NodeDeploymentID) > 1 {
+			if err := k.DNS.waitTTL(ctx); err != nil {
+				return errors.Wrap(err, "couldn't wait for the old dns records to expire, the old gateway is kept")
+			}
+		}
+	}
 	k.NodeDeploymentID, err = k.deployer.Deploy(ctx, sub, k.NodeDeploymentID, newDeployments)
 	if k.ID == "" && k.NodeDeploymentID[k.Node] != 0 {
 		k.ID = strconv.FormatUint(k.NodeDeploymentID[k.Node], 10)
 	}
+	if err == nil && k.DNS != nil {
+		err = errors.Wrap(k.DNS.removeOld(ctx, k.Gw.FQDN), "couldn't delete the dns records of the old domain")
+	}
 	return err
 }
 
@@ -174,6 +215,9 @@ func (k *GatewayFQDNDeployer) Cancel(ctx context.Context, sub subi.SubstrateExt)
 	newDeployments := make(map[uint32]gridtypes.Deployment)
 
 	k.NodeDeploymentID, err = k.deployer.Deploy(ctx, sub, k.NodeDeploymentID, newDeployments)
+	if err == nil && k.DNS != nil {
+		err = errors.Wrap(k.DNS.Provider.DeleteRecords(ctx, k.Gw.FQDN), "couldn't delete the dns records of the domain")
+	}
 
 	return err
 }
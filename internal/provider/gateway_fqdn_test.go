@@ -54,6 +54,11 @@ func TestValidatNodeReachable(t *testing.T) {
 		},
 		ncPool: pool,
 		Node:   11,
+		Gw: workloads.GatewayFQDNProxy{
+			Name:     "name",
+			FQDN:     "name.com",
+			Backends: []zos.Backend{"http://1.1.1.1"},
+		},
 	}
 	err = gw.Validate(context.TODO(), sub)
 	assert.NoError(t, err)
@@ -124,7 +129,7 @@ func TestDeploy(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer: deployer,
@@ -174,7 +179,7 @@ func TestUpdate(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -224,7 +229,7 @@ func TestUpdateFailed(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -273,7 +278,7 @@ func TestCancel(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -308,7 +313,7 @@ func TestCancelFailed(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -343,7 +348,7 @@ func TestSyncContracts(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -375,7 +380,7 @@ func TestSyncDeletedContracts(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -411,7 +416,7 @@ func TestSyncContractsFailure(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -445,7 +450,7 @@ func TestSyncFailureInContract(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -481,7 +486,7 @@ func TestSync(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -531,7 +536,7 @@ func TestSyncDeletedWorkload(t *testing.T) {
 		Gw: workloads.GatewayFQDNProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -0,0 +1,36 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"log"
+
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/deployer"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+// gatewayMigrating checks whether the gateway is moving to a node it's not deployed on yet while it's still deployed on others
+func gatewayMigrating(nodeDeploymentID map[uint32]uint64, node uint32) bool {
+	if _, ok := nodeDeploymentID[node]; ok {
+		return false
+	}
+	return len(nodeDeploymentID) != 0
+}
+
+// migrateGateway deploys the gateway on its new node leaving the old deployments as they are, so the proxy stays up until the new one is ready.
+// the old deployments are canceled by the next deploy. if the old deployments can't be fetched (e.g. their node is down) there's nothing to keep up and they're left to be replaced
+func migrateGateway(ctx context.Context, sub subi.SubstrateExt, d deployer.Deployer, nodeDeploymentID map[uint32]uint64, node uint32, dl gridtypes.Deployment) (map[uint32]uint64, error) {
+	target, err := d.GetDeployments(ctx, sub, nodeDeploymentID)
+	if err != nil {
+		log.Printf("couldn't get the old gateway deployments, they'll be removed before deploying on node %d: %s", node, err)
+		return nodeDeploymentID, nil
+	}
+	target[node] = dl
+	current, err := d.Deploy(ctx, sub, nodeDeploymentID, target)
+	if err != nil {
+		return current, errors.Wrapf(err, "couldn't deploy the gateway on node %d, the old deployments are kept", node)
+	}
+	return current, nil
+}
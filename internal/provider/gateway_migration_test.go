@@ -0,0 +1,210 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	proxyTypes "github.com/threefoldtech/grid_proxy_server/pkg/types"
+	"github.com/threefoldtech/substrate-client"
+	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
+	mock "github.com/threefoldtech/terraform-provider-grid/internal/provider/mocks"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestGatewayMigrating(t *testing.T) {
+	assert.False(t, gatewayMigrating(map[uint32]uint64{}, 10))
+	assert.False(t, gatewayMigrating(map[uint32]uint64{10: 100}, 10))
+	assert.False(t, gatewayMigrating(map[uint32]uint64{10: 100, 20: 200}, 10))
+	assert.True(t, gatewayMigrating(map[uint32]uint64{20: 200}, 10))
+}
+
+func TestMigrateGateway(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	deployer := mock.NewMockDeployer(ctrl)
+	sub := mock.NewMockSubstrateExt(ctrl)
+	oldDl := workloads.NewDeployment(11)
+	oldDl.ContractID = 100
+	newDl := workloads.NewDeployment(11)
+
+	// the old deployment is left as is while the new one is created
+	deployer.EXPECT().
+		GetDeployments(gomock.Any(), sub, map[uint32]uint64{10: 100}).
+		Return(map[uint32]gridtypes.Deployment{10: oldDl}, nil)
+	deployer.EXPECT().
+		Deploy(gomock.Any(), sub, map[uint32]uint64{10: 100}, map[uint32]gridtypes.Deployment{10: oldDl, 20: newDl}).
+		Return(map[uint32]uint64{10: 100, 20: 200}, nil)
+	current, err := migrateGateway(context.Background(), sub, deployer, map[uint32]uint64{10: 100}, 20, newDl)
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint32]uint64{10: 100, 20: 200}, current)
+
+	// the old node is down so there's nothing to keep
+	deployer.EXPECT().
+		GetDeployments(gomock.Any(), sub, map[uint32]uint64{10: 100}).
+		Return(nil, errors.New("node is down"))
+	current, err = migrateGateway(context.Background(), sub, deployer, map[uint32]uint64{10: 100}, 20, newDl)
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint32]uint64{10: 100}, current)
+}
+
+func TestNameMigration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	identity, err := substrate.NewIdentityFromEd25519Phrase(Words)
+	assert.NoError(t, err)
+	deployer := mock.NewMockDeployer(ctrl)
+	sub := mock.NewMockSubstrateExt(ctrl)
+	cl := mock.NewRMBMockClient(ctrl)
+	pool := mock.NewMockNodeClientGetter(ctrl)
+	gw := GatewayNameDeployer{
+		APIClient: &apiClient{
+			identity: identity,
+			twin_id:  11,
+		},
+		Node: 20,
+		Gw: workloads.GatewayNameProxy{
+			Name:     "name",
+			Backends: []zos.Backend{"http://1.1.1.1"},
+		},
+		deployer:         deployer,
+		NodeDeploymentID: map[uint32]uint64{10: 100},
+		NameContractID:   300,
+		ncPool:           pool,
+	}
+	dls, err := gw.GenerateVersionlessDeployments(context.Background())
+	assert.NoError(t, err)
+	oldDl := workloads.NewDeployment(11)
+
+	pool.EXPECT().
+		GetNodeClient(sub, uint32(20)).
+		Return(client.NewNodeClient(12, cl), nil)
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.system.version", gomock.Any(), gomock.Any()).
+		Return(nil)
+	sub.EXPECT().
+		InvalidateNameContract(gomock.Any(), identity, uint64(300), "name").
+		Return(uint64(300), nil)
+	gomock.InOrder(
+		deployer.EXPECT().
+			GetDeployments(gomock.Any(), sub, map[uint32]uint64{10: 100}).
+			Return(map[uint32]gridtypes.Deployment{10: oldDl}, nil),
+		deployer.EXPECT().
+			Deploy(gomock.Any(), sub, map[uint32]uint64{10: 100}, map[uint32]gridtypes.Deployment{10: oldDl, 20: dls[20]}).
+			Return(map[uint32]uint64{10: 100, 20: 200}, nil),
+		deployer.EXPECT().
+			Deploy(gomock.Any(), sub, map[uint32]uint64{10: 100, 20: 200}, dls).
+			Return(map[uint32]uint64{20: 200}, nil),
+	)
+	err = gw.Deploy(context.Background(), sub)
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint32]uint64{20: 200}, gw.NodeDeploymentID)
+	assert.Equal(t, uint64(300), gw.NameContractID)
+}
+
+func TestFQDNMigrationWaitsTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	deployer := mock.NewMockDeployer(ctrl)
+	sub := mock.NewMockSubstrateExt(ctrl)
+	cl := mock.NewRMBMockClient(ctrl)
+	pool := mock.NewMockNodeClientGetter(ctrl)
+	gridClient := mock.NewMockClient(ctrl)
+	provider := &fakeDNSProvider{records: map[string][]net.IP{}}
+	gw := GatewayFQDNDeployer{
+		APIClient: &apiClient{twin_id: 11, grid_client: gridClient},
+		Node:      20,
+		Gw: workloads.GatewayFQDNProxy{
+			Name:     "name",
+			FQDN:     "gw.example.com",
+			Backends: []zos.Backend{"http://1.1.1.1"},
+		},
+		DNS:              &GatewayDNS{Provider: provider, TTL: 100 * time.Millisecond},
+		deployer:         deployer,
+		NodeDeploymentID: map[uint32]uint64{10: 100},
+		ncPool:           pool,
+	}
+	dls, err := gw.GenerateVersionlessDeployments(context.Background())
+	assert.NoError(t, err)
+	oldDl := workloads.NewDeployment(11)
+
+	pool.EXPECT().
+		GetNodeClient(sub, uint32(20)).
+		Return(client.NewNodeClient(12, cl), nil).
+		AnyTimes()
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.system.version", gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.network.public_config_get", gomock.Any(), gomock.Any()).
+		Return(errors.New("timeout")).
+		AnyTimes()
+	gridClient.EXPECT().
+		Node(uint32(20)).
+		Return(proxyTypes.NodeWithNestedCapacity{PublicConfig: proxyTypes.PublicConfig{Ipv4: "185.206.122.33/24"}}, nil).
+		AnyTimes()
+
+	var pointed time.Time
+	gomock.InOrder(
+		deployer.EXPECT().
+			GetDeployments(gomock.Any(), sub, map[uint32]uint64{10: 100}).
+			Return(map[uint32]gridtypes.Deployment{10: oldDl}, nil),
+		deployer.EXPECT().
+			Deploy(gomock.Any(), sub, map[uint32]uint64{10: 100}, map[uint32]gridtypes.Deployment{10: oldDl, 20: dls[20]}).
+			Return(map[uint32]uint64{10: 100, 20: 200}, nil),
+		deployer.EXPECT().
+			Deploy(gomock.Any(), sub, map[uint32]uint64{10: 100, 20: 200}, dls).
+			DoAndReturn(func(ctx context.Context, sub subi.SubstrateExt, old map[uint32]uint64, new map[uint32]gridtypes.Deployment) (map[uint32]uint64, error) {
+				assert.Contains(t, provider.records, "gw.example.com", "the records point to the new node first")
+				pointed = time.Now()
+				return map[uint32]uint64{20: 200}, nil
+			}),
+	)
+	start := time.Now()
+	err = gw.Deploy(context.Background(), sub)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, pointed.Sub(start), 100*time.Millisecond, "the old gateway is kept for the records ttl")
+	assert.Equal(t, map[uint32]uint64{20: 200}, gw.NodeDeploymentID)
+
+	// the old gateway is kept if the wait is interrupted
+	gw.Node = 30
+	gw.NodeDeploymentID = map[uint32]uint64{20: 200}
+	gw.DNS.TTL = time.Hour
+	pool.EXPECT().
+		GetNodeClient(sub, uint32(30)).
+		Return(client.NewNodeClient(13, cl), nil).
+		AnyTimes()
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(13), "zos.system.version", gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(13), "zos.network.public_config_get", gomock.Any(), gomock.Any()).
+		Return(errors.New("timeout"))
+	gridClient.EXPECT().
+		Node(uint32(30)).
+		Return(proxyTypes.NodeWithNestedCapacity{PublicConfig: proxyTypes.PublicConfig{Ipv4: "185.206.122.34/24"}}, nil)
+	deployer.EXPECT().
+		GetDeployments(gomock.Any(), sub, map[uint32]uint64{20: 200}).
+		Return(map[uint32]gridtypes.Deployment{20: dls[20]}, nil)
+	deployer.EXPECT().
+		Deploy(gomock.Any(), sub, map[uint32]uint64{20: 200}, gomock.Any()).
+		Return(map[uint32]uint64{20: 200, 30: 300}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = gw.Deploy(ctx, sub)
+	assert.Error(t, err)
+	assert.Equal(t, map[uint32]uint64{20: 200, 30: 300}, gw.NodeDeploymentID)
+}
@@ -28,6 +28,12 @@ type GatewayNameDeployer struct {
 	Description      string
 	NodeDeploymentID map[uint32]uint64
 	NameContractID   uint64
+	NodeSelection    GatewayNodeSelection
+
+	// NameContract is the contract of a grid_name_contract reserving the name, the gateway neither creates nor cancels it
+	NameContract uint64
+	// reservedNameContract is true if the contract of the state belongs to a grid_name_contract
+	reservedNameContract bool
 
 	APIClient *apiClient
 	ncPool    client.NodeClientGetter
@@ -40,9 +46,10 @@ func NewGatewayNameDeployer(d *schema.ResourceData, apiClient *apiClient) (Gatew
 	for idx, n := range backendsIf {
 		backends[idx] = zos.Backend(n.(string))
 	}
-	nodeDeploymentIDIf := d.Get("node_deployment_id").(map[string]interface{})
+	// the old value still holds the deployments planned to be canceled
+	nodeDeploymentIDIf, _ := d.GetChange("node_deployment_id")
 	nodeDeploymentID := make(map[uint32]uint64)
-	for node, id := range nodeDeploymentIDIf {
+	for node, id := range nodeDeploymentIDIf.(map[string]interface{}) {
 		nodeInt, err := strconv.ParseUint(node, 10, 32)
 		if err != nil {
 			return GatewayNameDeployer{}, errors.Wrap(err, "couldn't parse node id")
@@ -50,16 +57,8 @@ func NewGatewayNameDeployer(d *schema.ResourceData, apiClient *apiClient) (Gatew
 		deploymentID := uint64(id.(int))
 		nodeDeploymentID[uint32(nodeInt)] = deploymentID
 	}
-	pool := client.NewNodeClientPool(apiClient.rmb)
-	deploymentData := DeploymentData{
-		Name:        d.Get("name").(string),
-		Type:        "gateway",
-		ProjectName: d.Get("solution_type").(string),
-	}
-	deploymentDataStr, err := json.Marshal(deploymentData)
-	if err != nil {
-		log.Printf("error parsing deploymentdata: %s", err.Error())
-	}
+	oldNameContract, _ := d.GetChange("name_contract")
+	pool, gwDeployer := newGatewayDeployer(apiClient, d.Get("name").(string), d.Get("solution_type").(string))
 	deployer := GatewayNameDeployer{
 		Gw: workloads.GatewayNameProxy{
 			Name:           d.Get("name").(string),
@@ -67,20 +66,41 @@ func NewGatewayNameDeployer(d *schema.ResourceData, apiClient *apiClient) (Gatew
 			FQDN:           d.Get("fqdn").(string),
 			TLSPassthrough: d.Get("tls_passthrough").(bool),
 		},
-		ID:               d.Id(),
-		Description:      d.Get("description").(string),
-		Node:             uint32(d.Get("node").(int)),
-		NodeDeploymentID: nodeDeploymentID,
-		NameContractID:   uint64(d.Get("name_contract_id").(int)),
+		ID:                   d.Id(),
+		Description:          d.Get("description").(string),
+		Node:                 uint32(d.Get("node").(int)),
+		NodeDeploymentID:     nodeDeploymentID,
+		NameContractID:       uint64(d.Get("name_contract_id").(int)),
+		NameContract:         uint64(d.Get("name_contract").(int)),
+		NodeSelection:        NewGatewayNodeSelection(d),
+		reservedNameContract: oldNameContract.(int) != 0,
 
 		APIClient: apiClient,
 		ncPool:    pool,
-		deployer:  deployer.NewDeployer(apiClient.identity, apiClient.twin_id, apiClient.grid_client, pool, true, nil, string(deploymentDataStr)),
+		deployer:  gwDeployer,
 	}
 	return deployer, nil
 }
 
+// newGatewayDeployer returns the node client pool and the deployer of a gateway deployment
+func newGatewayDeployer(apiClient *apiClient, name string, solutionType string) (client.NodeClientGetter, deployer.Deployer) {
+	pool := client.NewNodeClientPool(apiClient.rmb)
+	deploymentData := DeploymentData{
+		Name:        name,
+		Type:        "gateway",
+		ProjectName: solutionType,
+	}
+	deploymentDataStr, err := json.Marshal(deploymentData)
+	if err != nil {
+		log.Printf("error parsing deploymentdata: %s", err.Error())
+	}
+	return pool, deployer.NewDeployer(apiClient.identity, apiClient.twin_id, apiClient.grid_client, pool, true, nil, string(deploymentDataStr))
+}
+
 func (k *GatewayNameDeployer) Validate(ctx context.Context, sub subi.SubstrateExt) error {
+	if err := k.Gw.Validate(); err != nil {
+		return err
+	}
 	return client.AreNodesUp(ctx, sub, []uint32{k.Node}, k.ncPool)
 }
 
@@ -145,13 +165,25 @@ func (k *GatewayNameDeployer) InvalidateNameContract(ctx context.Context, sub su
 	)
 	return
 }
-func (k *GatewayNameDeployer) Deploy(ctx context.Context, sub subi.SubstrateExt) error {
-	if err := k.Validate(ctx, sub); err != nil {
-		return err
+
+// ensureNameContract uses the reserved name contract if given, otherwise it creates the name contract owned by the gateway
+func (k *GatewayNameDeployer) ensureNameContract(ctx context.Context, sub subi.SubstrateExt) (err error) {
+	if k.reservedNameContract {
+		// the contract of the state isn't the gateway's to cancel
+		k.NameContractID = 0
 	}
-	newDeployments, err := k.GenerateVersionlessDeployments(ctx)
-	if err != nil {
-		return errors.Wrap(err, "couldn't generate deployments data")
+	if k.NameContract != 0 {
+		if err := validateNameContract(sub, k.APIClient.twin_id, k.NameContract, k.Gw.Name); err != nil {
+			return err
+		}
+		if k.NameContractID != 0 && k.NameContractID != k.NameContract {
+			if err := sub.EnsureContractCanceled(k.APIClient.identity, k.NameContractID); err != nil {
+				return errors.Wrapf(err, "couldn't cancel the former name contract %d", k.NameContractID)
+			}
+		}
+		k.NameContractID = k.NameContract
+		k.reservedNameContract = true
+		return nil
 	}
 	if err := k.InvalidateNameContract(ctx, sub); err != nil {
 		return err
@@ -162,10 +194,38 @@ func (k *GatewayNameDeployer) Deploy(ctx context.Context, sub subi.SubstrateExt)
 			return err
 		}
 	}
+	k.reservedNameContract = false
+	return nil
+}
+
+func (k *GatewayNameDeployer) Deploy(ctx context.Context, sub subi.SubstrateExt) error {
+	if k.NodeSelection.Auto {
+		node, err := k.NodeSelection.ensureNode(ctx, sub, k.APIClient, k.ncPool, k.Node)
+		if err != nil {
+			return errors.Wrap(err, "couldn't pick a gateway node")
+		}
+		k.Node = node
+	}
+	if err := k.Validate(ctx, sub); err != nil {
+		return err
+	}
+	newDeployments, err := k.GenerateVersionlessDeployments(ctx)
+	if err != nil {
+		return errors.Wrap(err, "couldn't generate deployments data")
+	}
+	if err := k.ensureNameContract(ctx, sub); err != nil {
+		return err
+	}
 	if k.ID == "" {
 		// create the resource if the contract is created
 		k.ID = uuid.New().String()
 	}
+	if gatewayMigrating(k.NodeDeploymentID, k.Node) {
+		k.NodeDeploymentID, err = migrateGateway(ctx, sub, k.deployer, k.NodeDeploymentID, k.Node, newDeployments[k.Node])
+		if err != nil {
+			return err
+		}
+	}
 	k.NodeDeploymentID, err = k.deployer.Deploy(ctx, sub, k.NodeDeploymentID, newDeployments)
 	return err
 }
@@ -213,7 +273,7 @@ func (k *GatewayNameDeployer) Cancel(ctx context.Context, sub subi.SubstrateExt)
 	if err != nil {
 		return err
 	}
-	if k.NameContractID != 0 {
+	if k.NameContractID != 0 && !k.reservedNameContract {
 		if err := sub.EnsureContractCanceled(k.APIClient.identity, k.NameContractID); err != nil {
 			return err
 		}
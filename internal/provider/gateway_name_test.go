@@ -52,6 +52,11 @@ func TestNameValidateNodeNotReachable(t *testing.T) {
 		},
 		ncPool: pool,
 		Node:   11,
+		Gw: workloads.GatewayNameProxy{
+			Name:     "name",
+			FQDN:     "name.com",
+			Backends: []zos.Backend{"http://1.1.1.1"},
+		},
 	}
 	err = gw.Validate(context.TODO(), sub)
 	assert.Error(t, err)
@@ -91,6 +96,11 @@ func TestNameValidateNodeReachable(t *testing.T) {
 		},
 		ncPool: pool,
 		Node:   11,
+		Gw: workloads.GatewayNameProxy{
+			Name:     "name",
+			FQDN:     "name.com",
+			Backends: []zos.Backend{"http://1.1.1.1"},
+		},
 	}
 	err = gw.Validate(context.TODO(), sub)
 	assert.NoError(t, err)
@@ -162,7 +172,7 @@ func TestNameDeploy(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		ncPool:   pool,
@@ -214,7 +224,7 @@ func TestNameUpdate(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -269,7 +279,7 @@ func TestNameUpdateFailed(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -323,7 +333,7 @@ func TestNameCancel(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -364,7 +374,7 @@ func TestNameCancelDeploymentsFailed(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -399,7 +409,7 @@ func TestNameCancelContractsFailed(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		deployer:         deployer,
@@ -440,7 +450,7 @@ func TestNameSyncContracts(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -477,7 +487,7 @@ func TestNameSyncDeletedContracts(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -517,7 +527,7 @@ func TestNameSyncContractsFailure(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -554,7 +564,7 @@ func TestNameSync(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -609,7 +619,7 @@ func TestNameSyncDeletedWorkload(t *testing.T) {
 		Gw: workloads.GatewayNameProxy{
 			Name:           "name",
 			TLSPassthrough: false,
-			Backends:       []zos.Backend{"https://1.1.1.1", "http://2.2.2.2"},
+			Backends:       []zos.Backend{"http://1.1.1.1", "http://2.2.2.2"},
 			FQDN:           "name.com",
 		},
 		NodeDeploymentID: map[uint32]uint64{10: 100},
@@ -640,3 +650,46 @@ func TestNameSyncDeletedWorkload(t *testing.T) {
 	assert.Equal(t, gw.ID, "123")
 	assert.Equal(t, gw.Gw, workloads.GatewayNameProxy{})
 }
+
+func TestNameReservedContract(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	defer ctrl.Finish()
+
+	identity, err := substrate.NewIdentityFromEd25519Phrase(Words)
+	assert.NoError(t, err)
+	deployer := mock.NewMockDeployer(ctrl)
+	sub := mock.NewMockSubstrateExt(ctrl)
+	gw := GatewayNameDeployer{
+		APIClient: &apiClient{
+			identity: identity,
+			twin_id:  11,
+		},
+		Node: 10,
+		Gw: workloads.GatewayNameProxy{
+			Name:     "name",
+			Backends: []zos.Backend{"http://1.1.1.1"},
+		},
+		deployer:         deployer,
+		NodeDeploymentID: map[uint32]uint64{10: 100},
+		NameContractID:   200,
+		NameContract:     300,
+	}
+	// the name contract created by the gateway is replaced by the reserved one
+	sub.EXPECT().GetContract(uint64(300)).Return(nameContract(11, "name"), nil)
+	sub.EXPECT().EnsureContractCanceled(identity, uint64(200)).Return(nil)
+	err = gw.ensureNameContract(context.Background(), sub)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(300), gw.NameContractID)
+
+	// the reserved name contract outlives the gateway
+	deployer.EXPECT().Deploy(
+		gomock.Any(),
+		sub,
+		map[uint32]uint64{10: 100},
+		map[uint32]gridtypes.Deployment{},
+	).Return(map[uint32]uint64{}, nil)
+	err = gw.Cancel(context.Background(), sub)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(300), gw.NameContractID)
+}
@@ -0,0 +1,129 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	proxy "github.com/threefoldtech/grid_proxy_server/pkg/client"
+	proxyTypes "github.com/threefoldtech/grid_proxy_server/pkg/types"
+	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+)
+
+// GatewayNodeSelection picks the gateway node when it's not set by the user and keeps it stable until it becomes unavailable
+type GatewayNodeSelection struct {
+	// Auto is true if the node is picked by the provider
+	Auto    bool
+	Farm    string
+	Country string
+}
+
+func NewGatewayNodeSelection(d *schema.ResourceData) GatewayNodeSelection {
+	// the raw config is only available on create and update, which are the only actions that pick nodes
+	raw := d.GetRawConfig()
+	auto := false
+	if !raw.IsNull() && raw.IsKnown() {
+		auto = raw.GetAttr("node").IsNull()
+	}
+	return GatewayNodeSelection{
+		Auto:    auto,
+		Farm:    d.Get("farm").(string),
+		Country: d.Get("country").(string),
+	}
+}
+
+// gatewayNodeCustomizeDiff plans picking another node if the node picked by the provider is down,
+// the given computed attributes depend on the node and are planned as unknown with it
+func gatewayNodeCustomizeDiff(computed ...string) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		apiClient, ok := meta.(*apiClient)
+		if !ok {
+			return fmt.Errorf("failed to cast meta into api client")
+		}
+		if err := planGatewayNodeReplacement(ctx, d, apiClient.substrateConn, client.NewNodeClientPool(apiClient.rmb), computed); err != nil {
+			return err
+		}
+		return planLeftoverGatewayRemoval(d)
+	}
+}
+
+// planLeftoverGatewayRemoval plans an update canceling the deployments left on other nodes by an interrupted migration
+func planLeftoverGatewayRemoval(d *schema.ResourceDiff) error {
+	nodeDeploymentID := d.Get("node_deployment_id").(map[string]interface{})
+	if len(nodeDeploymentID) <= 1 || !d.NewValueKnown("node") {
+		return nil
+	}
+	node := fmt.Sprint(d.Get("node").(int))
+	kept := map[string]interface{}{}
+	if id, ok := nodeDeploymentID[node]; ok {
+		kept[node] = id
+	}
+	return d.SetNew("node_deployment_id", kept)
+}
+
+func planGatewayNodeReplacement(ctx context.Context, d *schema.ResourceDiff, sub subi.SubstrateExt, ncPool client.NodeClientGetter, computed []string) error {
+	raw := d.GetRawConfig()
+	if d.Id() == "" || raw.IsNull() || !raw.IsKnown() || !raw.GetAttr("node").IsNull() {
+		return nil
+	}
+	node := uint32(d.Get("node").(int))
+	if node == 0 {
+		return nil
+	}
+	if err := client.AreNodesUp(ctx, sub, []uint32{node}, ncPool); err == nil {
+		return nil
+	}
+	log.Printf("gateway node %d is unavailable, planning to pick another one", node)
+	for _, key := range append([]string{"node"}, computed...) {
+		if err := d.SetNewComputed(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureNode returns the current node if it's still up, otherwise picks a new one
+func (s *GatewayNodeSelection) ensureNode(ctx context.Context, sub subi.SubstrateExt, apiClient *apiClient, ncPool client.NodeClientGetter, node uint32) (uint32, error) {
+	if node != 0 {
+		err := client.AreNodesUp(ctx, sub, []uint32{node}, ncPool)
+		if err == nil {
+			return node, nil
+		}
+		log.Printf("gateway node %d is unavailable, picking another one: %s", node, err)
+	}
+	return selectGatewayNode(apiClient.grid_client, apiClient.twin_id, s.Farm, s.Country)
+}
+
+// selectGatewayNode picks a random up node with a domain available for the twin, optionally in the given farm and country
+func selectGatewayNode(gridClient proxy.Client, twinID uint32, farm string, country string) (uint32, error) {
+	status := "up"
+	hasDomain := true
+	twin := uint64(twinID)
+	filter := proxyTypes.NodeFilter{
+		Status:       &status,
+		Domain:       &hasDomain,
+		AvailableFor: &twin,
+	}
+	if farm != "" {
+		filter.FarmName = &farm
+	}
+	if country != "" {
+		filter.Country = &country
+	}
+	nodes, _, err := gridClient.Nodes(filter, proxyTypes.Limit{
+		Size: 50,
+		Page: 1,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't list gateway nodes from the grid proxy")
+	}
+	if len(nodes) == 0 {
+		return 0, errors.New("couldn't find an up node with a domain to be used as a gateway")
+	}
+	return uint32(nodes[rand.Intn(len(nodes))].NodeID), nil
+}
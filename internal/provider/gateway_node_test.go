@@ -0,0 +1,188 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	proxyTypes "github.com/threefoldtech/grid_proxy_server/pkg/types"
+	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
+	mock "github.com/threefoldtech/terraform-provider-grid/internal/provider/mocks"
+)
+
+func TestSelectGatewayNode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gridClient := mock.NewMockClient(ctrl)
+	gridClient.EXPECT().Nodes(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(filter proxyTypes.NodeFilter, limit proxyTypes.Limit) ([]proxyTypes.Node, int, error) {
+			assert.Equal(t, "up", *filter.Status)
+			assert.True(t, *filter.Domain)
+			assert.Equal(t, "freefarm", *filter.FarmName)
+			assert.Nil(t, filter.Country)
+			return []proxyTypes.Node{{NodeID: 7}}, 1, nil
+		})
+	node, err := selectGatewayNode(gridClient, 11, "freefarm", "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(7), node)
+
+	gridClient.EXPECT().Nodes(gomock.Any(), gomock.Any()).Return([]proxyTypes.Node{}, 0, nil)
+	_, err = selectGatewayNode(gridClient, 11, "", "Egypt")
+	assert.Error(t, err, "no gateway nodes")
+}
+
+func TestEnsureGatewayNode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sub := mock.NewMockSubstrateExt(ctrl)
+	cl := mock.NewRMBMockClient(ctrl)
+	pool := mock.NewMockNodeClientGetter(ctrl)
+	gridClient := mock.NewMockClient(ctrl)
+	apiClient := &apiClient{
+		twin_id:     11,
+		grid_client: gridClient,
+	}
+	selection := GatewayNodeSelection{Auto: true, Country: "Belgium"}
+
+	pool.EXPECT().
+		GetNodeClient(sub, uint32(7)).
+		Return(client.NewNodeClient(12, cl), nil).
+		Times(2)
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.system.version", gomock.Any(), gomock.Any()).
+		Return(nil)
+	node, err := selection.ensureNode(context.Background(), sub, apiClient, pool, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(7), node, "the up node should be kept")
+
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.system.version", gomock.Any(), gomock.Any()).
+		Return(errors.New("couldn't reach node"))
+	gridClient.EXPECT().Nodes(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(filter proxyTypes.NodeFilter, limit proxyTypes.Limit) ([]proxyTypes.Node, int, error) {
+			assert.Equal(t, "Belgium", *filter.Country)
+			return []proxyTypes.Node{{NodeID: 8}}, 1, nil
+		})
+	node, err = selection.ensureNode(context.Background(), sub, apiClient, pool, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(8), node, "the unavailable node should be replaced")
+}
+
+func TestPlanGatewayNodeReplacement(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sub := mock.NewMockSubstrateExt(ctrl)
+	cl := mock.NewRMBMockClient(ctrl)
+	pool := mock.NewMockNodeClientGetter(ctrl)
+	r := &schema.Resource{
+		Schema: resourceGatewayNameProxy().Schema,
+		CustomizeDiff: func(ctx context.Context, d *schema.ResourceDiff, i interface{}) error {
+			return planGatewayNodeReplacement(ctx, d, sub, pool, []string{"fqdn"})
+		},
+	}
+	config := func(attrs map[string]interface{}) (*terraform.ResourceConfig, cty.Value) {
+		raw := map[string]cty.Value{}
+		for key, value := range attrs {
+			switch v := value.(type) {
+			case string:
+				raw[key] = cty.StringVal(v)
+			case int:
+				raw[key] = cty.NumberIntVal(int64(v))
+			case []interface{}:
+				raw[key] = cty.ListVal([]cty.Value{cty.StringVal(v[0].(string))})
+			}
+		}
+		val, err := r.CoreConfigSchema().CoerceValue(cty.ObjectVal(raw))
+		assert.NoError(t, err)
+		return terraform.NewResourceConfigRaw(attrs), val
+	}
+	st := &terraform.InstanceState{
+		ID: "gw",
+		Attributes: map[string]string{
+			"name":                 "gw",
+			"solution_type":        "Gateway",
+			"node":                 "7",
+			"fqdn":                 "gw.node7.grid.tf",
+			"tls_passthrough":      "false",
+			"backends.#":           "1",
+			"backends.0":           "http://1.1.1.1",
+			"node_deployment_id.%": "1",
+			"node_deployment_id.7": "70",
+		},
+	}
+	ctx := context.Background()
+
+	pool.EXPECT().
+		GetNodeClient(sub, uint32(7)).
+		Return(client.NewNodeClient(12, cl), nil).
+		Times(2)
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.system.version", gomock.Any(), gomock.Any()).
+		Return(nil)
+	cfg, raw := config(map[string]interface{}{"name": "gw", "backends": []interface{}{"http://1.1.1.1"}})
+	st.RawConfig = raw
+	diff, err := r.Diff(ctx, st, cfg, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, diff, "the up node should be kept")
+
+	cl.EXPECT().
+		Call(gomock.Any(), uint32(12), "zos.system.version", gomock.Any(), gomock.Any()).
+		Return(errors.New("couldn't reach node"))
+	diff, err = r.Diff(ctx, st, cfg, nil)
+	assert.NoError(t, err)
+	assert.True(t, diff.Attributes["node"].NewComputed, "another node should be picked")
+	assert.True(t, diff.Attributes["fqdn"].NewComputed)
+
+	// a node set by the user isn't replaced
+	cfg, raw = config(map[string]interface{}{"name": "gw", "node": 7, "backends": []interface{}{"http://1.1.1.1"}})
+	st.RawConfig = raw
+	diff, err = r.Diff(ctx, st, cfg, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, diff)
+}
+
+func TestPlanLeftoverGatewayRemoval(t *testing.T) {
+	r := &schema.Resource{
+		Schema: resourceGatewayFQDNProxy().Schema,
+		CustomizeDiff: func(ctx context.Context, d *schema.ResourceDiff, i interface{}) error {
+			return planLeftoverGatewayRemoval(d)
+		},
+	}
+	attrs := map[string]interface{}{"name": "gw", "node": 7, "fqdn": "gw.example.com", "backends": []interface{}{"http://1.1.1.1"}}
+	st := &terraform.InstanceState{
+		ID: "gw",
+		Attributes: map[string]string{
+			"name":                 "gw",
+			"solution_type":        "Gateway",
+			"node":                 "7",
+			"fqdn":                 "gw.example.com",
+			"tls_passthrough":      "false",
+			"backends.#":           "1",
+			"backends.0":           "http://1.1.1.1",
+			"node_deployment_id.%": "1",
+			"node_deployment_id.7": "70",
+		},
+	}
+	ctx := context.Background()
+	diff, err := r.Diff(ctx, st, terraform.NewResourceConfigRaw(attrs), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, diff)
+
+	// an interrupted migration left the deployment on node 5
+	st.Attributes["node_deployment_id.%"] = "2"
+	st.Attributes["node_deployment_id.5"] = "50"
+	diff, err = r.Diff(ctx, st, terraform.NewResourceConfigRaw(attrs), nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, diff)
+	assert.True(t, diff.Attributes["node_deployment_id.5"].NewRemoved)
+	assert.Equal(t, "1", diff.Attributes["node_deployment_id.%"].New)
+}
@@ -0,0 +1,237 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// k3sReservedEnv are the env vars set by the provider that can't be overridden by env_vars
+var k3sReservedEnv = []string{
+	"SSH_KEY",
+	"K3S_TOKEN",
+	"K3S_DATA_DIR",
+	"K3S_FLANNEL_IFACE",
+	"K3S_NODE_NAME",
+	"K3S_URL",
+}
+
+// diskNameMatch restricts the disk names so the disk workloads named <node>_<disk> are unambiguous,
+// k8s node names can't have underscores
+var diskNameMatch = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+// K8sDisk is an extra data disk of a k8s node
+type K8sDisk struct {
+	Name       string
+	Size       int
+	MountPoint string
+}
+
+// K8sNodeCustomization is the extra env vars, extra disks and vm options of a k8s node
+type K8sNodeCustomization struct {
+	EnvVars map[string]string
+	Disks   []K8sDisk
+
+	Description string
+	Corex       bool
+	RootfsSize  int
+	Zlogs       []workloads.Zlog
+}
+
+// withK8sNodeCustomization adds the node customization attributes to the schema of a k8s node block
+func withK8sNodeCustomization(s map[string]*schema.Schema) map[string]*schema.Schema {
+	s["env_vars"] = &schema.Schema{
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "Extra environment variables of the node, the ones set by the provider can't be overridden",
+	}
+	s["disks"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Description: "Extra data disks of the node",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "Disk name, unique per node and alphanumeric. The disk is deployed as the node name and the disk name separated by an underscore",
+				},
+				"size": {
+					Type:        schema.TypeInt,
+					Required:    true,
+					Description: "Disk size in GBs",
+				},
+				"mount_point": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "Where the disk is mounted in the node",
+				},
+			},
+		},
+	}
+	s["description"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Default:     "",
+		Description: "Description of the node's vm",
+	}
+	s["corex"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Enable corex",
+	}
+	s["rootfs_size"] = &schema.Schema{
+		Type:        schema.TypeInt,
+		Optional:    true,
+		Description: "Rootfs size in MB",
+	}
+	s["zlogs"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Description: "Zlogs is a utility workload that allows you to stream `zmachine` logs to a remote location.",
+		Elem: &schema.Schema{
+			Type:        schema.TypeString,
+			Description: "Url of the remote machine receiving logs."},
+	}
+	return s
+}
+
+func NewK8sNodeCustomization(m map[string]interface{}) K8sNodeCustomization {
+	envVars := make(map[string]string)
+	for k, v := range m["env_vars"].(map[string]interface{}) {
+		envVars[k] = v.(string)
+	}
+	disks := make([]K8sDisk, 0)
+	for _, d := range m["disks"].([]interface{}) {
+		disk := d.(map[string]interface{})
+		disks = append(disks, K8sDisk{
+			Name:       disk["name"].(string),
+			Size:       disk["size"].(int),
+			MountPoint: disk["mount_point"].(string),
+		})
+	}
+	zlogs := make([]workloads.Zlog, 0)
+	for _, v := range m["zlogs"].([]interface{}) {
+		zlogs = append(zlogs, workloads.Zlog{Output: v.(string)})
+	}
+	return K8sNodeCustomization{
+		EnvVars:     envVars,
+		Disks:       disks,
+		Description: m["description"].(string),
+		Corex:       m["corex"].(bool),
+		RootfsSize:  m["rootfs_size"].(int),
+		Zlogs:       zlogs,
+	}
+}
+
+// NewK8sNodeCustomizationFromVM loads the node customization from its vm
+func NewK8sNodeCustomizationFromVM(vm *workloads.VM, diskSizes map[string]int) K8sNodeCustomization {
+	c := K8sNodeCustomization{
+		EnvVars:     make(map[string]string),
+		Disks:       make([]K8sDisk, 0),
+		Description: vm.Description,
+		Corex:       vm.Corex,
+		RootfsSize:  vm.RootfsSize,
+		Zlogs:       make([]workloads.Zlog, 0),
+	}
+	c.Zlogs = append(c.Zlogs, vm.Zlogs...)
+	for k, v := range vm.EnvVars {
+		if !Contains(k3sReservedEnv, k) {
+			c.EnvVars[k] = v
+		}
+	}
+	for _, mount := range vm.Mounts {
+		diskName := strings.TrimPrefix(mount.DiskName, vm.Name+"_")
+		if diskName == mount.DiskName {
+			continue
+		}
+		c.Disks = append(c.Disks, K8sDisk{
+			Name:       diskName,
+			Size:       diskSizes[mount.DiskName],
+			MountPoint: mount.MountPoint,
+		})
+	}
+	return c
+}
+
+func (c *K8sNodeCustomization) dictify(res map[string]interface{}) {
+	envVars := make(map[string]interface{})
+	for k, v := range c.EnvVars {
+		envVars[k] = v
+	}
+	disks := make([]interface{}, 0)
+	for _, d := range c.Disks {
+		disks = append(disks, map[string]interface{}{
+			"name":        d.Name,
+			"size":        d.Size,
+			"mount_point": d.MountPoint,
+		})
+	}
+	res["env_vars"] = envVars
+	res["disks"] = disks
+	zlogs := make([]interface{}, 0)
+	for _, zlog := range c.Zlogs {
+		zlogs = append(zlogs, zlog.Output)
+	}
+	res["description"] = c.Description
+	res["corex"] = c.Corex
+	res["rootfs_size"] = c.RootfsSize
+	res["zlogs"] = zlogs
+}
+
+func (c *K8sNodeCustomization) validate(name string) error {
+	for k := range c.EnvVars {
+		if Contains(k3sReservedEnv, k) {
+			return fmt.Errorf("env var %s of %s is set by the provider and can't be overridden", k, name)
+		}
+	}
+	names := make(map[string]bool)
+	for _, d := range c.Disks {
+		if !diskNameMatch.MatchString(d.Name) {
+			return fmt.Errorf("disk name %s of %s should only have letters and digits", d.Name, name)
+		}
+		if names[d.Name] {
+			return fmt.Errorf("disk names of %s must be unique: %s occurred more than once", name, d.Name)
+		}
+		names[d.Name] = true
+		if d.Size < 1 {
+			return fmt.Errorf("disk %s of %s size should be at least 1 GB", d.Name, name)
+		}
+	}
+	return nil
+}
+
+// customizeVM applies the customization to the node's vm and returns the extra disks workloads
+func (c *K8sNodeCustomization) customizeVM(vm *workloads.VM) []gridtypes.Workload {
+	vm.Description = c.Description
+	vm.Corex = c.Corex
+	vm.RootfsSize = c.RootfsSize
+	vm.Zlogs = c.Zlogs
+	for k, v := range c.EnvVars {
+		if _, ok := vm.EnvVars[k]; !ok {
+			vm.EnvVars[k] = v
+		}
+	}
+	disks := make([]gridtypes.Workload, 0)
+	for _, d := range c.Disks {
+		diskName := fmt.Sprintf("%s_%s", vm.Name, d.Name)
+		disks = append(disks, gridtypes.Workload{
+			Name:    gridtypes.Name(diskName),
+			Version: 0,
+			Type:    zos.ZMountType,
+			Data: gridtypes.MustMarshal(zos.ZMount{
+				Size: gridtypes.Unit(d.Size) * gridtypes.Gigabyte,
+			}),
+		})
+		vm.Mounts = append(vm.Mounts, workloads.Mount{DiskName: diskName, MountPoint: d.MountPoint})
+	}
+	return disks
+}
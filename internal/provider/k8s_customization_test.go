@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+func TestK8sNodeCustomizationRoundTrip(t *testing.T) {
+	c := K8sNodeCustomization{
+		EnvVars: map[string]string{"HTTP_PROXY": "http://proxy:3128"},
+		Disks:   []K8sDisk{{Name: "data", Size: 100, MountPoint: "/data"}},
+	}
+	c.Description = "storage node"
+	c.Corex = true
+	c.RootfsSize = 2048
+	c.Zlogs = []workloads.Zlog{{Output: "redis://logs:6379"}}
+	vm := workloads.VM{
+		Name: "w0",
+		EnvVars: map[string]string{
+			"K3S_TOKEN":  "token",
+			"HTTP_PROXY": "overridden",
+		},
+		Mounts: []workloads.Mount{{DiskName: "w0disk", MountPoint: "/mydisk"}},
+	}
+	disks := c.customizeVM(&vm)
+	assert.Len(t, disks, 1)
+	assert.Equal(t, gridtypes.Name("w0_data"), disks[0].Name)
+	assert.Equal(t, "overridden", vm.EnvVars["HTTP_PROXY"], "env vars set already shouldn't be overridden")
+	assert.Equal(t, 2048, vm.RootfsSize)
+
+	loaded := NewK8sNodeCustomizationFromVM(&vm, map[string]int{"w0disk": 10, "w0_data": 100})
+	c.EnvVars["HTTP_PROXY"] = "overridden"
+	assert.Equal(t, c, loaded)
+}
+
+func TestK8sNodeCustomizationValidate(t *testing.T) {
+	c := K8sNodeCustomization{EnvVars: map[string]string{"HTTP_PROXY": "http://proxy:3128"}, Disks: []K8sDisk{{Name: "data", Size: 10}}}
+	assert.NoError(t, c.validate("w0"))
+
+	c = K8sNodeCustomization{EnvVars: map[string]string{"K3S_URL": "https://10.1.2.2:6443"}}
+	assert.Error(t, c.validate("w0"), "reserved env var")
+
+	c = K8sNodeCustomization{Disks: []K8sDisk{{Name: "data_0", Size: 10}}}
+	assert.Error(t, c.validate("w0"), "disk name with the separator")
+
+	c = K8sNodeCustomization{Disks: []K8sDisk{{Name: "data", Size: 10}, {Name: "data", Size: 20}}}
+	assert.Error(t, c.validate("w0"), "duplicate disk name")
+}
@@ -0,0 +1,214 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/deployer"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+// K8sRollingUpdate is the strategy of updating the nodes of an existing cluster
+type K8sRollingUpdate struct {
+	// BatchSize is the number of zos nodes updated at once after the master's node
+	BatchSize int
+	// ReadinessCheck waits for the updated k8s nodes to be ready before moving to the next batch
+	ReadinessCheck bool
+}
+
+func NewK8sRollingUpdate(m map[string]interface{}) *K8sRollingUpdate {
+	return &K8sRollingUpdate{
+		BatchSize:      m["batch_size"].(int),
+		ReadinessCheck: m["readiness_check"].(bool),
+	}
+}
+
+// workloadDataHashes returns a mapping from workload name to a hash of its type and data regardless of its version
+func workloadDataHashes(dl gridtypes.Deployment) (map[string]string, error) {
+	hashes := make(map[string]string)
+	for _, w := range dl.Workloads {
+		data, err := w.WorkloadData()
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't load workload %s data", w.Name)
+		}
+		md5Hash := md5.New()
+		if _, err := fmt.Fprintf(md5Hash, "%s%s", w.Type, w.Description); err != nil {
+			return nil, err
+		}
+		if err := data.Challenge(md5Hash); err != nil {
+			return nil, errors.Wrapf(err, "couldn't get a hash for workload %s", w.Name)
+		}
+		hashes[string(w.Name)] = string(md5Hash.Sum(nil))
+	}
+	return hashes, nil
+}
+
+// deploymentChanged checks whether applying the versionless deployment changes the current one
+func deploymentChanged(current gridtypes.Deployment, dl gridtypes.Deployment) (bool, error) {
+	if !deployer.SameWorkloadsNames(current, dl) {
+		return true, nil
+	}
+	currentHashes, err := workloadDataHashes(current)
+	if err != nil {
+		return false, err
+	}
+	newHashes, err := workloadDataHashes(dl)
+	if err != nil {
+		return false, err
+	}
+	for name, hash := range newHashes {
+		if currentHashes[name] != hash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rolloutBatches returns the changed nodes that already have deployments in the order they should be updated: the master's node alone then the rest in batches
+func (k *K8sDeployer) rolloutBatches(current map[uint32]gridtypes.Deployment, newDeployments map[uint32]gridtypes.Deployment) ([][]uint32, error) {
+	changed := make([]uint32, 0)
+	for node, dl := range newDeployments {
+		currentDl, ok := current[node]
+		if !ok {
+			continue
+		}
+		ok, err := deploymentChanged(currentDl, dl)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't compare node %d deployments", node)
+		}
+		if ok {
+			changed = append(changed, node)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i] < changed[j] })
+
+	batches := make([][]uint32, 0)
+	rest := make([]uint32, 0)
+	for _, node := range changed {
+		if node == k.Master.Node {
+			batches = append(batches, []uint32{node})
+		} else {
+			rest = append(rest, node)
+		}
+	}
+	for len(rest) > 0 {
+		size := k.RollingUpdate.BatchSize
+		if size > len(rest) {
+			size = len(rest)
+		}
+		batches = append(batches, rest[:size])
+		rest = rest[size:]
+	}
+	return batches, nil
+}
+
+// rollingDeploy updates the changed nodes batch by batch, waiting for each batch to be deployed (and ready if configured) before the next one.
+// nodes to be created or deleted are handled after all batches. the rollout aborts on the first failure leaving the former batches updated
+func (k *K8sDeployer) rollingDeploy(ctx context.Context, sub subi.SubstrateExt, newDeployments map[uint32]gridtypes.Deployment) (map[uint32]uint64, error) {
+	currentIDs := make(map[uint32]uint64)
+	for node, id := range k.NodeDeploymentID {
+		currentIDs[node] = id
+	}
+	target, err := k.deployer.GetDeployments(ctx, sub, currentIDs)
+	if err != nil {
+		return currentIDs, errors.Wrap(err, "couldn't get current deployments to roll out the update")
+	}
+	batches, err := k.rolloutBatches(target, newDeployments)
+	if err != nil {
+		return currentIDs, err
+	}
+	for _, batch := range batches {
+		log.Printf("rolling out k8s update to nodes %v", batch)
+		batchIDs := make(map[uint32]uint64)
+		for _, node := range batch {
+			target[node] = newDeployments[node]
+			batchIDs[node] = currentIDs[node]
+		}
+		currentIDs, err = k.deployer.Deploy(ctx, sub, currentIDs, target)
+		if err != nil {
+			return currentIDs, errors.Wrapf(err, "couldn't update nodes %v, aborting the rollout", batch)
+		}
+		// the deployed ones replace the versionless deployments to be left as is by the next batches
+		deployed, err := k.deployer.GetDeployments(ctx, sub, batchIDs)
+		if err != nil {
+			return currentIDs, errors.Wrapf(err, "couldn't get the updated deployments of nodes %v", batch)
+		}
+		for node, dl := range deployed {
+			target[node] = dl
+		}
+		if k.RollingUpdate.ReadinessCheck {
+			if err := k.waitNodesReady(ctx, batch); err != nil {
+				return currentIDs, errors.Wrapf(err, "k8s nodes on nodes %v aren't ready, aborting the rollout", batch)
+			}
+		}
+	}
+	for node, dl := range newDeployments {
+		if _, ok := target[node]; !ok {
+			target[node] = dl
+		}
+	}
+	for node := range target {
+		if _, ok := newDeployments[node]; !ok {
+			delete(target, node)
+		}
+	}
+	return k.deployer.Deploy(ctx, sub, currentIDs, target)
+}
+
+// waitNodesReady waits for the k8s nodes hosted on the given zos nodes to report ready, it's checked from the master over ssh
+func (k *K8sDeployer) waitNodesReady(ctx context.Context, nodes []uint32) error {
+	names := make([]string, 0)
+	for _, node := range nodes {
+		if k.Master.Node == node {
+			names = append(names, k.Master.Name)
+		}
+		for _, w := range k.Workers {
+			if w.Node == node {
+				names = append(names, w.Name)
+			}
+		}
+	}
+	ip := k.Master.reachableIP()
+	if ip == "" {
+		return fmt.Errorf("master %s has no ip to check the nodes readiness through", k.Master.Name)
+	}
+	auth, release, err := sshAuthMethods(k.SSHPrivateKey)
+	if err != nil {
+		return err
+	}
+	defer release()
+	hostKey := k.hostKeyCallback()
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 5 * time.Second
+	b.MaxInterval = 30 * time.Second
+	b.MaxElapsedTime = 10 * time.Minute
+	for _, name := range names {
+		cmd := fmt.Sprintf(`k3s kubectl get node %s -o jsonpath='{.status.conditions[?(@.type=="Ready")].status}'`, name)
+		err := backoff.Retry(func() error {
+			status, err := runRemoteCommand(ip, auth, hostKey, cmd)
+			if err != nil && permanentSSHError(err) {
+				return backoff.Permanent(err)
+			} else if err != nil {
+				return err
+			}
+			if strings.TrimSpace(status) != "True" {
+				return fmt.Errorf("node %s isn't ready yet", name)
+			}
+			return nil
+		}, backoff.WithContext(b, ctx))
+		if err != nil {
+			return errors.Wrapf(err, "node %s didn't become ready", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func k8sDeployment(version uint32, diskSize gridtypes.Unit) gridtypes.Deployment {
+	return gridtypes.Deployment{
+		Version: version,
+		Workloads: []gridtypes.Workload{
+			{
+				Version: version,
+				Name:    "wdisk",
+				Type:    zos.ZMountType,
+				Data: gridtypes.MustMarshal(zos.ZMount{
+					Size: diskSize * gridtypes.Gigabyte,
+				}),
+			},
+		},
+	}
+}
+
+func TestDeploymentChanged(t *testing.T) {
+	changed, err := deploymentChanged(k8sDeployment(3, 10), k8sDeployment(0, 10))
+	assert.NoError(t, err)
+	assert.False(t, changed, "versions shouldn't be considered")
+
+	changed, err = deploymentChanged(k8sDeployment(3, 10), k8sDeployment(0, 20))
+	assert.NoError(t, err)
+	assert.True(t, changed, "disk size changed")
+
+	renamed := k8sDeployment(0, 10)
+	renamed.Workloads[0].Name = "w2disk"
+	changed, err = deploymentChanged(k8sDeployment(3, 10), renamed)
+	assert.NoError(t, err)
+	assert.True(t, changed, "workload names changed")
+}
+
+func TestRolloutBatches(t *testing.T) {
+	k := K8sDeployer{
+		Master:        &K8sNodeData{Node: 3},
+		RollingUpdate: &K8sRollingUpdate{BatchSize: 2},
+	}
+	current := map[uint32]gridtypes.Deployment{
+		1: k8sDeployment(1, 10),
+		2: k8sDeployment(1, 10),
+		3: k8sDeployment(1, 10),
+		4: k8sDeployment(1, 10),
+		5: k8sDeployment(1, 10),
+	}
+	newDeployments := map[uint32]gridtypes.Deployment{
+		1: k8sDeployment(0, 20),
+		2: k8sDeployment(0, 20),
+		3: k8sDeployment(0, 20),
+		4: k8sDeployment(0, 10), // unchanged
+		5: k8sDeployment(0, 20),
+		6: k8sDeployment(0, 20), // new node
+	}
+	batches, err := k.rolloutBatches(current, newDeployments)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]uint32{{3}, {1, 2}, {5}}, batches)
+}
@@ -0,0 +1,172 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const (
+	k3sConfigPath = "/etc/rancher/k3s/k3s.yaml"
+	k3sAPIPort    = "6443"
+
+	errHostKeyMismatch = "ssh host key mismatch"
+)
+
+var kubeconfigServer = regexp.MustCompile(`(?m)^(\s*server:\s*)\S+$`)
+
+// reachableIP returns the first ip of the node that's reachable from outside the grid, the private ip is only reachable through wireguard
+func (k *K8sNodeData) reachableIP() string {
+	for _, ip := range []string{k.ComputedIP, k.ComputedIP6, k.YggIP, k.IP} {
+		if ip == "" {
+			continue
+		}
+		if parsed, _, err := net.ParseCIDR(ip); err == nil {
+			return parsed.String()
+		}
+		if parsed := net.ParseIP(ip); parsed != nil {
+			return parsed.String()
+		}
+	}
+	return ""
+}
+
+// rewriteKubeconfigServer points the clusters of the kubeconfig to the api server at the given ip
+func rewriteKubeconfigServer(kubeconfig string, ip string) string {
+	server := fmt.Sprintf("https://%s", net.JoinHostPort(ip, k3sAPIPort))
+	return kubeconfigServer.ReplaceAllString(kubeconfig, "${1}"+server)
+}
+
+// sshAuthMethods uses the private key if given, otherwise the keys of the running ssh agent.
+// the returned function releases the agent connection
+func sshAuthMethods(privateKey string) ([]ssh.AuthMethod, func(), error) {
+	if privateKey != "" {
+		key, err := ssh.ParsePrivateKey([]byte(privateKey))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "couldn't parse ssh private key")
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(key)}, func() {}, nil
+	}
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, errors.New("no ssh private key is provided and SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't connect to ssh agent")
+	}
+	return []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(conn).Signers)}, func() { conn.Close() }, nil
+}
+
+// sshHostKey returns the master's pinned host key, the key trusted on first use is dropped if the master vm is replaced
+func sshHostKey(d *schema.ResourceData) string {
+	hostKey := d.Get("ssh_host_key").(string)
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.GetAttr("ssh_host_key").IsNull() {
+		return hostKey
+	}
+	if d.HasChange("master.0.node") || d.HasChange("master.0.name") {
+		return ""
+	}
+	return hostKey
+}
+
+// hostKeyCallback checks the master's host key against the pinned one.
+// if no key is pinned, the first key seen is trusted and pinned
+func (k *K8sDeployer) hostKeyCallback() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		seen := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+		if k.SSHHostKey == "" {
+			k.SSHHostKey = seen
+			return nil
+		}
+		pinned, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k.SSHHostKey))
+		if err != nil {
+			return errors.Wrap(err, "couldn't parse ssh_host_key")
+		}
+		if !bytes.Equal(pinned.Marshal(), key.Marshal()) {
+			return fmt.Errorf("%s of %s: expected %s, got %s", errHostKeyMismatch, hostname, k.SSHHostKey, seen)
+		}
+		return nil
+	}
+}
+
+// permanentSSHError is true for the errors retrying the connection won't fix
+func permanentSSHError(err error) bool {
+	return strings.Contains(err.Error(), "unable to authenticate") ||
+		strings.Contains(err.Error(), errHostKeyMismatch)
+}
+
+// runRemoteCommand runs the command on the host over ssh as root and returns its output
+func runRemoteCommand(addr string, auth []ssh.AuthMethod, hostKey ssh.HostKeyCallback, cmd string) (string, error) {
+	config := &ssh.ClientConfig{
+		User:            "root",
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         10 * time.Second,
+	}
+	client, err := ssh.Dial("tcp", net.JoinHostPort(addr, "22"), config)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	var out bytes.Buffer
+	session.Stdout = &out
+	if err := session.Run(cmd); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// fetchKubeconfig waits for k3s to write the kubeconfig on the master and returns it with the server pointing to the master's reachable ip
+func fetchKubeconfig(ctx context.Context, master *K8sNodeData, privateKey string, hostKey ssh.HostKeyCallback) (string, error) {
+	ip := master.reachableIP()
+	if ip == "" {
+		return "", fmt.Errorf("master %s has no ip to fetch the kubeconfig through", master.Name)
+	}
+	auth, release, err := sshAuthMethods(privateKey)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 5 * time.Second
+	b.MaxInterval = 30 * time.Second
+	b.MaxElapsedTime = 5 * time.Minute
+	var kubeconfig string
+	err = backoff.Retry(func() error {
+		config, err := runRemoteCommand(ip, auth, hostKey, fmt.Sprintf("cat %s", k3sConfigPath))
+		if err != nil && permanentSSHError(err) {
+			return backoff.Permanent(err)
+		} else if err != nil {
+			return err
+		}
+		if strings.TrimSpace(config) == "" {
+			return errors.New("kubeconfig is empty")
+		}
+		kubeconfig = config
+		return nil
+	}, backoff.WithContext(b, ctx))
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't read %s from master %s at %s", k3sConfigPath, master.Name, ip)
+	}
+	return rewriteKubeconfigServer(kubeconfig, ip), nil
+}
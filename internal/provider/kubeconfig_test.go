@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestReachableIP(t *testing.T) {
+	master := K8sNodeData{IP: "10.1.2.2", YggIP: "300:1::2", ComputedIP: "185.1.2.3/24"}
+	assert.Equal(t, "185.1.2.3", master.reachableIP())
+
+	master.ComputedIP = ""
+	assert.Equal(t, "300:1::2", master.reachableIP())
+
+	master.YggIP = ""
+	assert.Equal(t, "10.1.2.2", master.reachableIP())
+
+	master.IP = ""
+	assert.Equal(t, "", master.reachableIP())
+}
+
+func TestRewriteKubeconfigServer(t *testing.T) {
+	kubeconfig := `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: abc
+    server: https://127.0.0.1:6443
+  name: default
+`
+	assert.Equal(t, `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: abc
+    server: https://185.1.2.3:6443
+  name: default
+`, rewriteKubeconfigServer(kubeconfig, "185.1.2.3"))
+	assert.Contains(t, rewriteKubeconfigServer(kubeconfig, "300:1::2"), "server: https://[300:1::2]:6443\n")
+}
+
+func hostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	first, second := hostKey(t), hostKey(t)
+	k := K8sDeployer{}
+	callback := k.hostKeyCallback()
+	assert.NoError(t, callback("185.1.2.3:22", nil, first), "the first key is trusted")
+	assert.Equal(t, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(first))), k.SSHHostKey)
+	assert.NoError(t, callback("185.1.2.3:22", nil, first))
+
+	err := callback("185.1.2.3:22", nil, second)
+	assert.Error(t, err)
+	assert.True(t, permanentSSHError(err), "a changed host key isn't retried")
+
+	// a key set in the config is checked from the first connection
+	k = K8sDeployer{SSHHostKey: string(ssh.MarshalAuthorizedKey(second))}
+	assert.Error(t, k.hostKeyCallback()("185.1.2.3:22", nil, first))
+	assert.NoError(t, k.hostKeyCallback()("185.1.2.3:22", nil, second))
+}
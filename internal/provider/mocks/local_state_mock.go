@@ -253,10 +253,10 @@ func (mr *MockNetworkMockRecorder) DeleteNodeSubnet(nodeID interface{}) *gomock.
 }
 
 // GetDeploymentIPs mocks base method.
-func (m *MockNetwork) GetDeploymentIPs(nodeID uint32, deploymentID string) []byte {
+func (m *MockNetwork) GetDeploymentIPs(nodeID uint32, deploymentID string) []uint32 {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetDeploymentIPs", nodeID, deploymentID)
-	ret0, _ := ret[0].([]byte)
+	ret0, _ := ret[0].([]uint32)
 	return ret0
 }
 
@@ -280,11 +280,25 @@ func (mr *MockNetworkMockRecorder) GetNodeIPs() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeIPs", reflect.TypeOf((*MockNetwork)(nil).GetNodeIPs))
 }
 
+// GetNodeDeploymentIDs mocks base method.
+func (m *MockNetwork) GetNodeDeploymentIDs() map[uint32]uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeDeploymentIDs")
+	ret0, _ := ret[0].(map[uint32]uint64)
+	return ret0
+}
+
+// GetNodeDeploymentIDs indicates an expected call of GetNodeDeploymentIDs.
+func (mr *MockNetworkMockRecorder) GetNodeDeploymentIDs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeDeploymentIDs", reflect.TypeOf((*MockNetwork)(nil).GetNodeDeploymentIDs))
+}
+
 // GetNodeIPsList mocks base method.
-func (m *MockNetwork) GetNodeIPsList(nodeID uint32) []byte {
+func (m *MockNetwork) GetNodeIPsList(nodeID uint32) []uint32 {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetNodeIPsList", nodeID)
-	ret0, _ := ret[0].([]byte)
+	ret0, _ := ret[0].([]uint32)
 	return ret0
 }
 
@@ -323,7 +337,7 @@ func (mr *MockNetworkMockRecorder) GetSubnets() *gomock.Call {
 }
 
 // SetDeploymentIPs mocks base method.
-func (m *MockNetwork) SetDeploymentIPs(nodeID uint32, deploymentID string, ips []byte) {
+func (m *MockNetwork) SetDeploymentIPs(nodeID uint32, deploymentID string, ips []uint32) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "SetDeploymentIPs", nodeID, deploymentID, ips)
 }
@@ -334,6 +348,18 @@ func (mr *MockNetworkMockRecorder) SetDeploymentIPs(nodeID, deploymentID, ips in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeploymentIPs", reflect.TypeOf((*MockNetwork)(nil).SetDeploymentIPs), nodeID, deploymentID, ips)
 }
 
+// SetNodeDeploymentIDs mocks base method.
+func (m *MockNetwork) SetNodeDeploymentIDs(ids map[uint32]uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetNodeDeploymentIDs", ids)
+}
+
+// SetNodeDeploymentIDs indicates an expected call of SetNodeDeploymentIDs.
+func (mr *MockNetworkMockRecorder) SetNodeDeploymentIDs(ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNodeDeploymentIDs", reflect.TypeOf((*MockNetwork)(nil).SetNodeDeploymentIDs), ids)
+}
+
 // SetNodeSubnet mocks base method.
 func (m *MockNetwork) SetNodeSubnet(nodeID uint32, subnet string) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,134 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+)
+
+// NameContract reserves a gateway name independently of the gateway workloads using it
+type NameContract struct {
+	ID         string
+	Name       string
+	ContractID uint64
+	// KeepOnDestroy leaves the contract on the chain when the resource is destroyed
+	KeepOnDestroy bool
+	// Adopted is true if the contract existed before the resource, it's never canceled by it
+	Adopted bool
+
+	APIClient *apiClient
+}
+
+func NewNameContract(d *schema.ResourceData, apiClient *apiClient) NameContract {
+	return NameContract{
+		ID:            d.Id(),
+		Name:          d.Get("name").(string),
+		ContractID:    uint64(d.Get("contract_id").(int)),
+		KeepOnDestroy: d.Get("keep_on_destroy").(bool),
+		Adopted:       d.Get("adopted").(bool),
+		APIClient:     apiClient,
+	}
+}
+
+func (n *NameContract) Marshal(d *schema.ResourceData) (errors error) {
+	err := d.Set("name", n.Name)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("contract_id", n.ContractID)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("keep_on_destroy", n.KeepOnDestroy)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("adopted", n.Adopted)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	d.SetId(n.ID)
+	return
+}
+
+// Deploy creates the name contract, an existing contract of the same name owned by the twin is adopted instead
+func (n *NameContract) Deploy(ctx context.Context, sub subi.SubstrateExt) error {
+	if n.ContractID == 0 {
+		id, err := sub.GetContractIDByNameRegistration(n.Name)
+		if err != nil && !errors.Is(err, subi.ErrNotFound) {
+			return errors.Wrapf(err, "couldn't check if name %s is reserved", n.Name)
+		}
+		if err == nil {
+			contract, err := sub.GetContract(id)
+			if err != nil {
+				return errors.Wrapf(err, "couldn't get name contract %d", id)
+			}
+			if contract.TwinID() != n.APIClient.twin_id {
+				return fmt.Errorf("name %s is already reserved by twin %d", n.Name, contract.TwinID())
+			}
+			n.ContractID = id
+			n.Adopted = true
+		}
+	}
+	if n.ContractID == 0 {
+		id, err := sub.CreateNameContract(n.APIClient.identity, n.Name)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't create name contract of %s", n.Name)
+		}
+		n.ContractID = id
+	}
+	n.ID = strconv.FormatUint(n.ContractID, 10)
+	return nil
+}
+
+func (n *NameContract) sync(ctx context.Context, sub subi.SubstrateExt, cl *apiClient) error {
+	valid, err := sub.IsValidContract(n.ContractID)
+	if err != nil {
+		return errors.Wrap(err, "couldn't sync name contract")
+	}
+	if !valid {
+		// delete resource in case the contract is canceled (reflects only on read)
+		n.ContractID = 0
+		n.ID = ""
+	}
+	return nil
+}
+
+func (n *NameContract) Cancel(ctx context.Context, sub subi.SubstrateExt) error {
+	if n.ContractID != 0 && !n.KeepOnDestroy && !n.Adopted {
+		if err := sub.EnsureContractCanceled(n.APIClient.identity, n.ContractID); err != nil {
+			return errors.Wrapf(err, "couldn't cancel name contract %d", n.ContractID)
+		}
+	}
+	n.ContractID = 0
+	n.ID = ""
+	return nil
+}
+
+// validateNameContract checks that the contract is a valid contract reserving the gateway name for the twin
+func validateNameContract(sub subi.SubstrateExt, twinID uint32, contractID uint64, name string) error {
+	contract, err := sub.GetContract(contractID)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't get name contract %d", contractID)
+	}
+	if !contract.IsCreated() {
+		return fmt.Errorf("name contract %d is not active", contractID)
+	}
+	if contract.TwinID() != twinID {
+		return fmt.Errorf("name contract %d is owned by twin %d", contractID, contract.TwinID())
+	}
+	if contract.Name() != name {
+		return fmt.Errorf("name contract %d reserves %s not %s", contractID, contract.Name(), name)
+	}
+	return nil
+}
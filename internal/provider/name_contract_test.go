@@ -0,0 +1,84 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	subdev "github.com/threefoldtech/substrate-client-dev"
+	mock "github.com/threefoldtech/terraform-provider-grid/internal/provider/mocks"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+)
+
+func nameContract(twinID uint32, name string) subi.Contract {
+	return &subi.DevContract{Contract: &subdev.Contract{
+		TwinID: types.U32(twinID),
+		State:  subdev.ContractState{IsCreated: true},
+		ContractType: subdev.ContractType{
+			IsNameContract: true,
+			NameContract:   subdev.NameContract{Name: name},
+		},
+	}}
+}
+
+func TestNameContractDeploy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sub := mock.NewMockSubstrateExt(ctrl)
+	n := NameContract{Name: "name", APIClient: &apiClient{twin_id: 11}}
+
+	sub.EXPECT().GetContractIDByNameRegistration("name").Return(uint64(0), subi.ErrNotFound)
+	sub.EXPECT().CreateNameContract(gomock.Any(), "name").Return(uint64(100), nil)
+	assert.NoError(t, n.Deploy(context.Background(), sub))
+	assert.Equal(t, uint64(100), n.ContractID)
+	assert.Equal(t, "100", n.ID)
+	assert.False(t, n.Adopted)
+
+	adopted := NameContract{Name: "name", APIClient: &apiClient{twin_id: 11}}
+	sub.EXPECT().GetContractIDByNameRegistration("name").Return(uint64(100), nil)
+	sub.EXPECT().GetContract(uint64(100)).Return(nameContract(11, "name"), nil)
+	assert.NoError(t, adopted.Deploy(context.Background(), sub))
+	assert.Equal(t, uint64(100), adopted.ContractID)
+	assert.True(t, adopted.Adopted)
+
+	taken := NameContract{Name: "name", APIClient: &apiClient{twin_id: 12}}
+	sub.EXPECT().GetContractIDByNameRegistration("name").Return(uint64(100), nil)
+	sub.EXPECT().GetContract(uint64(100)).Return(nameContract(11, "name"), nil)
+	assert.Error(t, taken.Deploy(context.Background(), sub))
+}
+
+func TestNameContractCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sub := mock.NewMockSubstrateExt(ctrl)
+	n := NameContract{Name: "name", ContractID: 100, ID: "100", APIClient: &apiClient{twin_id: 11}}
+	sub.EXPECT().EnsureContractCanceled(gomock.Any(), uint64(100)).Return(nil)
+	assert.NoError(t, n.Cancel(context.Background(), sub))
+	assert.Equal(t, uint64(0), n.ContractID)
+
+	// the contract is left on the chain
+	kept := NameContract{Name: "name", ContractID: 100, ID: "100", KeepOnDestroy: true, APIClient: &apiClient{twin_id: 11}}
+	assert.NoError(t, kept.Cancel(context.Background(), sub))
+	assert.Equal(t, "", kept.ID)
+
+	// the contract was reserved by another environment
+	adopted := NameContract{Name: "name", ContractID: 100, ID: "100", Adopted: true, APIClient: &apiClient{twin_id: 11}}
+	assert.NoError(t, adopted.Cancel(context.Background(), sub))
+	assert.Equal(t, "", adopted.ID)
+}
+
+func TestValidateNameContract(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sub := mock.NewMockSubstrateExt(ctrl)
+	sub.EXPECT().GetContract(uint64(100)).Return(nameContract(11, "name"), nil).Times(3)
+	assert.NoError(t, validateNameContract(sub, 11, 100, "name"))
+	assert.Error(t, validateNameContract(sub, 12, 100, "name"))
+	assert.Error(t, validateNameContract(sub, 11, 100, "other"))
+}
@@ -111,12 +111,15 @@ func New(version string, st state.StateI) (func() *schema.Provider, subi.Substra
 				"grid_gateway_domain": dataSourceGatewayDomain(),
 			},
 			ResourcesMap: map[string]*schema.Resource{
-				"grid_scheduler":  ReourceScheduler(),
-				"grid_deployment": resourceDeployment(),
-				"grid_network":    resourceNetwork(),
-				"grid_kubernetes": resourceKubernetes(),
-				"grid_name_proxy": resourceGatewayNameProxy(),
-				"grid_fqdn_proxy": resourceGatewayFQDNProxy(),
+				"grid_scheduler":     ReourceScheduler(),
+				"grid_deployment":    resourceDeployment(),
+				"grid_network":       resourceNetwork(),
+				"grid_kubernetes":    resourceKubernetes(),
+				"grid_name_proxy":    resourceGatewayNameProxy(),
+				"grid_name_contract": resourceNameContract(),
+				"grid_fqdn_proxy":    resourceGatewayFQDNProxy(),
+				"grid_vm_gateway":    resourceVMGateway(),
+				"grid_qsfs":          resourceQSFS(),
 			},
 		}
 		configFunc, sub := providerConfigure(st)
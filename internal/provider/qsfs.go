@@ -0,0 +1,496 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/deployer"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+const (
+	qsfsMetaMode = "user"
+	qsfsDataMode = "seq"
+)
+
+var yggNet = net.IPNet{IP: net.ParseIP("200::"), Mask: net.CIDRMask(7, 128)}
+
+// QSFSZDB is a zdb backend of the qsfs
+type QSFSZDB struct {
+	Name      string
+	Node      uint32
+	Mode      string
+	Namespace string
+	Address   string
+	// Status is the zdb status as seen by the qsfs, zdbs that are down are replaced on other nodes
+	Status string
+}
+
+// QSFSDeployer deploys the zdbs of a qsfs on the zdb nodes, then the qsfs and the vms mounting it in one deployment on the qsfs node
+type QSFSDeployer struct {
+	ID          string
+	Name        string
+	ZDBNodes    []uint32
+	MetaShards  int
+	DataShards  int
+	ZDBSize     int
+	ZDBPassword string
+	// ZDBs are the deployed zdbs, their nodes are kept as long as they're in ZDBNodes
+	ZDBs            []QSFSZDB
+	ZDBDeploymentID map[uint32]uint64
+	QSFS            workloads.QSFS
+	VMDeployment    DeploymentDeployer
+
+	APIClient   *apiClient
+	ncPool      client.NodeClientGetter
+	zdbDeployer deployer.Deployer
+}
+
+func NewQSFSDeployer(d *schema.ResourceData, apiClient *apiClient) (QSFSDeployer, error) {
+	name := d.Get("name").(string)
+	zdbNodes := make([]uint32, 0)
+	for _, node := range d.Get("zdb_nodes").([]interface{}) {
+		zdbNodes = append(zdbNodes, uint32(node.(int)))
+	}
+	// the zdbs are planned as unknown when one of them is down, so their current placement is taken from the state
+	oldZDBs, _ := d.GetChange("zdbs")
+	zdbs := make([]QSFSZDB, 0)
+	for _, zdb := range oldZDBs.([]interface{}) {
+		m := zdb.(map[string]interface{})
+		zdbs = append(zdbs, QSFSZDB{
+			Name:      m["name"].(string),
+			Node:      uint32(m["node"].(int)),
+			Mode:      m["mode"].(string),
+			Namespace: m["namespace"].(string),
+			Address:   m["address"].(string),
+			Status:    m["status"].(string),
+		})
+	}
+	zdbDeploymentID := make(map[uint32]uint64)
+	for node, id := range d.Get("zdb_deployment_id").(map[string]interface{}) {
+		nodeInt, err := strconv.ParseUint(node, 10, 32)
+		if err != nil {
+			return QSFSDeployer{}, errors.Wrap(err, "couldn't parse node id")
+		}
+		zdbDeploymentID[uint32(nodeInt)] = uint64(id.(int))
+	}
+
+	networkName := d.Get("network_name").(string)
+	node := uint32(d.Get("node").(int))
+	vms := make([]workloads.VM, 0)
+	for _, vm := range d.Get("vms").([]interface{}) {
+		data := workloads.NewVMFromSchema(vm.(map[string]interface{})).WithNetworkName(networkName)
+		vms = append(vms, *data)
+	}
+	network := apiClient.state.GetNetworkState().GetNetwork(networkName)
+	pool, qsfsDeployer := newQSFSDeployer(apiClient, name, d.Get("solution_type").(string))
+	encryptionKey := d.Get("encryption_key").(string)
+	return QSFSDeployer{
+		ID:              d.Id(),
+		Name:            name,
+		ZDBNodes:        zdbNodes,
+		MetaShards:      d.Get("meta_shards").(int),
+		DataShards:      d.Get("data_shards").(int),
+		ZDBSize:         d.Get("zdb_size").(int),
+		ZDBPassword:     d.Get("zdb_password").(string),
+		ZDBs:            zdbs,
+		ZDBDeploymentID: zdbDeploymentID,
+		QSFS: workloads.QSFS{
+			Name:                 name,
+			Description:          d.Get("description").(string),
+			Cache:                d.Get("cache").(int),
+			MinimalShards:        uint32(d.Get("minimal_shards").(int)),
+			ExpectedShards:       uint32(d.Get("data_shards").(int)),
+			MaxZDBDataDirSize:    uint32(d.Get("max_zdb_data_dir_size").(int)),
+			EncryptionAlgorithm:  "AES",
+			EncryptionKey:        encryptionKey,
+			CompressionAlgorithm: d.Get("compression_algorithm").(string),
+			Metadata: workloads.Metadata{
+				Type:                "zdb",
+				Prefix:              name,
+				EncryptionAlgorithm: "AES",
+				EncryptionKey:       encryptionKey,
+			},
+			MetricsEndpoint: d.Get("metrics_endpoint").(string),
+		},
+		VMDeployment: DeploymentDeployer{
+			Id:          d.Get("vm_deployment_id").(string),
+			Node:        node,
+			VMs:         vms,
+			QSFSs:       make([]workloads.QSFS, 0),
+			Disks:       make([]workloads.Disk, 0),
+			ZDBs:        make([]workloads.ZDB, 0),
+			IPRange:     network.GetNodeSubnet(node),
+			NetworkName: networkName,
+			APIClient:   apiClient,
+			ncPool:      pool,
+			deployer:    qsfsDeployer,
+		},
+		APIClient:   apiClient,
+		ncPool:      pool,
+		zdbDeployer: qsfsDeployer,
+	}, nil
+}
+
+// newQSFSDeployer returns the node client pool and the deployer of the qsfs deployments
+func newQSFSDeployer(apiClient *apiClient, name string, solutionType string) (client.NodeClientGetter, deployer.Deployer) {
+	pool := client.NewNodeClientPool(apiClient.rmb)
+	deploymentData := DeploymentData{
+		Name:        name,
+		Type:        "qsfs",
+		ProjectName: solutionType,
+	}
+	deploymentDataStr, err := json.Marshal(deploymentData)
+	if err != nil {
+		log.Printf("error parsing deploymentdata: %s", err.Error())
+	}
+	return pool, deployer.NewDeployer(apiClient.identity, apiClient.twin_id, apiClient.grid_client, pool, true, nil, string(deploymentDataStr))
+}
+
+func (q *QSFSDeployer) Validate(ctx context.Context, sub subi.SubstrateExt) error {
+	if len(q.ZDBNodes) == 0 {
+		return errors.New("at least one zdb node is required")
+	}
+	if q.MetaShards < 1 {
+		return errors.New("meta_shards must be at least 1")
+	}
+	if q.QSFS.MinimalShards < 1 || int(q.QSFS.MinimalShards) > q.DataShards {
+		return fmt.Errorf("minimal_shards must be between 1 and data_shards (%d)", q.DataShards)
+	}
+	if key, err := hex.DecodeString(q.QSFS.EncryptionKey); err != nil || len(key) != 32 {
+		return errors.New("encryption_key must be 32 bytes encoded as 64 hex characters")
+	}
+	if err := q.VMDeployment.validate(); err != nil {
+		return err
+	}
+	// only the nodes holding the zdbs after replacing the down ones have to be up
+	nodes := []uint32{q.VMDeployment.Node}
+	for _, zdb := range q.assignZDBs() {
+		if !Contains(nodes, zdb.Node) {
+			nodes = append(nodes, zdb.Node)
+		}
+	}
+	return client.AreNodesUp(ctx, sub, nodes, q.ncPool)
+}
+
+// zdbReplaceable checks whether a zdb on the given node can be moved to another of the zdb nodes
+func zdbReplaceable(zdbNodes []uint32, node uint32) bool {
+	for _, n := range zdbNodes {
+		if n != node {
+			return true
+		}
+	}
+	return false
+}
+
+// assignZDBs returns the desired zdbs. the zdbs keep their nodes if still in ZDBNodes and they're not down,
+// the new zdbs and the ones whose nodes were removed are assigned to the zdb nodes with the least zdbs.
+// the zdbs that are down are assigned to other nodes than theirs, the qsfs backends are swapped in place.
+// a down zdb is kept as is if there's no other node to move it to
+func (q *QSFSDeployer) assignZDBs() []QSFSZDB {
+	current := make(map[string]QSFSZDB)
+	for _, zdb := range q.ZDBs {
+		current[zdb.Name] = zdb
+	}
+	load := make(map[uint32]int)
+	for _, node := range q.ZDBNodes {
+		load[node] = 0
+	}
+	desired := make([]QSFSZDB, 0, q.MetaShards+q.DataShards)
+	for i := 0; i < q.MetaShards; i++ {
+		desired = append(desired, QSFSZDB{Name: fmt.Sprintf("%smeta%d", q.Name, i), Mode: qsfsMetaMode})
+	}
+	for i := 0; i < q.DataShards; i++ {
+		desired = append(desired, QSFSZDB{Name: fmt.Sprintf("%sdata%d", q.Name, i), Mode: qsfsDataMode})
+	}
+	unassigned := make([]int, 0)
+	downNodes := make(map[int]uint32)
+	for idx, zdb := range desired {
+		old, ok := current[zdb.Name]
+		if _, used := load[old.Node]; ok && used && (old.Status != workloads.BackendStatusDown || !zdbReplaceable(q.ZDBNodes, old.Node)) {
+			desired[idx] = old
+			load[old.Node]++
+			continue
+		}
+		if ok && old.Status == workloads.BackendStatusDown {
+			downNodes[idx] = old.Node
+		}
+		unassigned = append(unassigned, idx)
+	}
+	for _, idx := range unassigned {
+		best, found := uint32(0), false
+		for _, node := range q.ZDBNodes {
+			if downNode, ok := downNodes[idx]; ok && node == downNode {
+				continue
+			}
+			if !found || load[node] < load[best] {
+				best, found = node, true
+			}
+		}
+		desired[idx].Node = best
+		load[best]++
+	}
+	return desired
+}
+
+// zdbDeployments returns the deployments of the zdbs on each zdb node
+func (q *QSFSDeployer) zdbDeployments(zdbs []QSFSZDB) map[uint32]gridtypes.Deployment {
+	deployments := make(map[uint32]gridtypes.Deployment)
+	for _, zdb := range zdbs {
+		dl, ok := deployments[zdb.Node]
+		if !ok {
+			dl = workloads.NewDeployment(q.APIClient.twin_id)
+		}
+		w := workloads.ZDB{
+			Name:     zdb.Name,
+			Password: q.ZDBPassword,
+			Size:     q.ZDBSize,
+			Mode:     zdb.Mode,
+		}
+		dl.Workloads = append(dl.Workloads, w.GenerateZDBWorkload())
+		deployments[zdb.Node] = dl
+	}
+	return deployments
+}
+
+// zdbAddress returns the address the qsfs reaches the zdb through, the yggdrasil ip is preferred as it's reachable from any node
+func zdbAddress(zdb workloads.ZDB) string {
+	address := ""
+	for _, ip := range zdb.IPs {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			continue
+		}
+		if yggNet.Contains(parsed) {
+			return net.JoinHostPort(ip, fmt.Sprint(zdb.Port))
+		}
+		if address == "" {
+			address = net.JoinHostPort(ip, fmt.Sprint(zdb.Port))
+		}
+	}
+	return address
+}
+
+// loadZDBs updates the zdbs with the namespaces and addresses of their workloads, zdbs on unreachable nodes are left as is
+func (q *QSFSDeployer) loadZDBs(ctx context.Context, sub subi.SubstrateExt, zdbs []QSFSZDB) []QSFSZDB {
+	dls := make(map[uint32]gridtypes.Deployment)
+	for node, id := range q.ZDBDeploymentID {
+		nodeDls, err := q.zdbDeployer.GetDeployments(ctx, sub, map[uint32]uint64{node: id})
+		if err != nil {
+			log.Printf("couldn't get the zdbs deployment of node %d: %s", node, err)
+			continue
+		}
+		dls[node] = nodeDls[node]
+	}
+	for idx, zdb := range zdbs {
+		dl, ok := dls[zdb.Node]
+		if !ok {
+			continue
+		}
+		wl, err := dl.Get(gridtypes.Name(zdb.Name))
+		if err != nil || !wl.Result.State.IsOkay() {
+			zdbs[idx].Namespace = ""
+			zdbs[idx].Address = ""
+			continue
+		}
+		data, err := workloads.NewZDBFromWorkload(wl.Workload)
+		if err != nil {
+			log.Printf("error parsing zdb %s: %s", zdb.Name, err)
+			continue
+		}
+		zdbs[idx].Namespace = data.Namespace
+		zdbs[idx].Address = zdbAddress(data)
+	}
+	return zdbs
+}
+
+// zdbsStatus sets the status of the zdbs from the qsfs metrics, zdbs without a running workload are down
+func (q *QSFSDeployer) zdbsStatus(zdbs []QSFSZDB) {
+	for idx, zdb := range zdbs {
+		if zdb.Address == "" {
+			zdbs[idx].Status = workloads.BackendStatusDown
+			continue
+		}
+		zdbs[idx].Status = q.QSFS.BackendsStatus.Status(workloads.Backend{Address: zdb.Address, Namespace: zdb.Namespace})
+	}
+}
+
+// backends sets the metadata backends and the data group of the qsfs to the zdbs
+func (q *QSFSDeployer) backends(zdbs []QSFSZDB) error {
+	meta := make(workloads.Backends, 0)
+	data := make(workloads.Backends, 0)
+	for _, zdb := range zdbs {
+		if zdb.Address == "" {
+			return fmt.Errorf("zdb %s on node %d has no reachable address", zdb.Name, zdb.Node)
+		}
+		backend := workloads.Backend(zos.ZdbBackend{
+			Address:   zdb.Address,
+			Namespace: zdb.Namespace,
+			Password:  q.ZDBPassword,
+		})
+		if zdb.Mode == qsfsMetaMode {
+			meta = append(meta, backend)
+		} else {
+			data = append(data, backend)
+		}
+	}
+	q.QSFS.Metadata.Backends = meta
+	q.QSFS.Groups = workloads.Groups{{Backends: data}}
+	return nil
+}
+
+// keepOldZDBs adds the reachable deployments of the nodes removed from the zdb nodes to the new ones,
+// so the qsfs keeps its old backends until it's updated to use the new ones
+func (q *QSFSDeployer) keepOldZDBs(ctx context.Context, sub subi.SubstrateExt, deployments map[uint32]gridtypes.Deployment) map[uint32]gridtypes.Deployment {
+	target := make(map[uint32]gridtypes.Deployment)
+	for node, dl := range deployments {
+		target[node] = dl
+	}
+	for node, id := range q.ZDBDeploymentID {
+		if _, ok := target[node]; ok {
+			continue
+		}
+		dls, err := q.zdbDeployer.GetDeployments(ctx, sub, map[uint32]uint64{node: id})
+		if err != nil {
+			log.Printf("couldn't get the zdbs deployment of node %d, it'll be removed: %s", node, err)
+			continue
+		}
+		target[node] = dls[node]
+	}
+	return target
+}
+
+// Deploy deploys the zdbs first as their namespaces and addresses are needed to configure the qsfs,
+// then the qsfs with the vms. the zdbs of removed nodes are canceled after the qsfs stops using them
+func (q *QSFSDeployer) Deploy(ctx context.Context, sub subi.SubstrateExt) error {
+	if err := q.Validate(ctx, sub); err != nil {
+		return err
+	}
+	zdbs := q.assignZDBs()
+	deployments := q.zdbDeployments(zdbs)
+	var err error
+	q.ZDBDeploymentID, err = q.zdbDeployer.Deploy(ctx, sub, q.ZDBDeploymentID, q.keepOldZDBs(ctx, sub, deployments))
+	if q.ID == "" && len(q.ZDBDeploymentID) != 0 {
+		// create the resource if any zdb is deployed
+		q.ID = uuid.New().String()
+	}
+	if err != nil {
+		return errors.Wrap(err, "couldn't deploy the qsfs zdbs")
+	}
+	q.ZDBs = q.loadZDBs(ctx, sub, zdbs)
+	if err := q.backends(q.ZDBs); err != nil {
+		return err
+	}
+	q.VMDeployment.QSFSs = []workloads.QSFS{q.QSFS}
+	if err := q.VMDeployment.Deploy(ctx, sub); err != nil {
+		return errors.Wrap(err, "couldn't deploy the qsfs and its vms")
+	}
+	q.ZDBDeploymentID, err = q.zdbDeployer.Deploy(ctx, sub, q.ZDBDeploymentID, deployments)
+	return errors.Wrap(err, "couldn't remove the old zdbs")
+}
+
+func (q *QSFSDeployer) Cancel(ctx context.Context, sub subi.SubstrateExt) error {
+	if err := q.VMDeployment.Cancel(ctx, sub); err != nil {
+		return errors.Wrap(err, "couldn't cancel the qsfs deployment")
+	}
+	var err error
+	q.ZDBDeploymentID, err = q.zdbDeployer.Deploy(ctx, sub, q.ZDBDeploymentID, map[uint32]gridtypes.Deployment{})
+	if err != nil {
+		return errors.Wrap(err, "couldn't cancel the zdbs deployments")
+	}
+	q.ID = ""
+	return nil
+}
+
+func (q *QSFSDeployer) sync(ctx context.Context, sub subi.SubstrateExt, cl *apiClient) error {
+	if err := sub.DeleteInvalidContracts(q.ZDBDeploymentID); err != nil {
+		return errors.Wrap(err, "couldn't sync zdbs contracts")
+	}
+	if err := q.VMDeployment.sync(ctx, sub, cl); err != nil {
+		return errors.Wrap(err, "couldn't sync the qsfs deployment")
+	}
+	q.QSFS.MetricsEndpoint = ""
+	q.QSFS.BackendsStatus = nil
+	for _, qsfs := range q.VMDeployment.QSFSs {
+		if qsfs.Name == q.Name {
+			q.QSFS.MetricsEndpoint = qsfs.MetricsEndpoint
+			q.QSFS.BackendsStatus = qsfs.BackendsStatus
+		}
+	}
+	q.ZDBs = q.loadZDBs(ctx, sub, q.ZDBs)
+	q.zdbsStatus(q.ZDBs)
+	if q.VMDeployment.Id == "" && len(q.ZDBDeploymentID) == 0 {
+		// delete resource in case nothing is active (reflects only on read)
+		q.ID = ""
+	}
+	return nil
+}
+
+func (q *QSFSDeployer) Marshal(d *schema.ResourceData) (errors error) {
+	zdbs := make([]interface{}, 0)
+	for _, zdb := range q.ZDBs {
+		zdbs = append(zdbs, map[string]interface{}{
+			"name":      zdb.Name,
+			"node":      int(zdb.Node),
+			"mode":      zdb.Mode,
+			"namespace": zdb.Namespace,
+			"address":   zdb.Address,
+			"status":    zdb.Status,
+		})
+	}
+	zdbDeploymentID := make(map[string]interface{})
+	for node, id := range q.ZDBDeploymentID {
+		zdbDeploymentID[fmt.Sprintf("%d", node)] = int(id)
+	}
+	vms := make([]interface{}, 0)
+	for _, vm := range q.VMDeployment.VMs {
+		vms = append(vms, vm.Dictify())
+	}
+
+	err := d.Set("zdbs", zdbs)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("zdb_deployment_id", zdbDeploymentID)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("vms", vms)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("vm_deployment_id", q.VMDeployment.Id)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("ip_range", q.VMDeployment.IPRange)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("metrics_endpoint", q.QSFS.MetricsEndpoint)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	d.SetId(q.ID)
+	return
+}
@@ -0,0 +1,254 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	mock "github.com/threefoldtech/terraform-provider-grid/internal/provider/mocks"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/state"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestQSFSValidate(t *testing.T) {
+	q := QSFSDeployer{
+		ZDBNodes:   []uint32{10},
+		MetaShards: 4,
+		DataShards: 4,
+		QSFS: workloads.QSFS{
+			MinimalShards: 2,
+			EncryptionKey: "abc",
+		},
+	}
+	err := q.Validate(context.Background(), nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "encryption_key")
+
+	q.QSFS.EncryptionKey = strings.Repeat("0", 64)
+	q.QSFS.MinimalShards = 5
+	err = q.Validate(context.Background(), nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "minimal_shards")
+
+	q.QSFS.MinimalShards = 2
+	q.ZDBNodes = nil
+	err = q.Validate(context.Background(), nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "zdb node")
+}
+
+func TestAssignZDBs(t *testing.T) {
+	q := QSFSDeployer{
+		Name:       "q",
+		ZDBNodes:   []uint32{10, 20},
+		MetaShards: 2,
+		DataShards: 2,
+	}
+	zdbs := q.assignZDBs()
+	assert.Equal(t, []QSFSZDB{
+		{Name: "qmeta0", Node: 10, Mode: qsfsMetaMode},
+		{Name: "qmeta1", Node: 20, Mode: qsfsMetaMode},
+		{Name: "qdata0", Node: 10, Mode: qsfsDataMode},
+		{Name: "qdata1", Node: 20, Mode: qsfsDataMode},
+	}, zdbs)
+
+	// the zdbs keep their nodes and addresses when a node is added
+	zdbs[0].Address = "[300::1]:9900"
+	q.ZDBs = zdbs
+	q.ZDBNodes = []uint32{10, 20, 30}
+	assert.Equal(t, zdbs, q.assignZDBs())
+
+	// only the zdbs of the removed node move
+	q.ZDBNodes = []uint32{10, 30}
+	moved := q.assignZDBs()
+	assert.Equal(t, zdbs[0], moved[0])
+	assert.Equal(t, zdbs[2], moved[2])
+	assert.Equal(t, QSFSZDB{Name: "qmeta1", Node: 30, Mode: qsfsMetaMode}, moved[1])
+	assert.Equal(t, QSFSZDB{Name: "qdata1", Node: 30, Mode: qsfsDataMode}, moved[3])
+
+	// a zdb that's down is replaced on another node
+	q.ZDBs = moved
+	q.ZDBs[0].Status = workloads.BackendStatusDown
+	replaced := q.assignZDBs()
+	assert.Equal(t, QSFSZDB{Name: "qmeta0", Node: 30, Mode: qsfsMetaMode}, replaced[0])
+	assert.Equal(t, moved[1:], replaced[1:])
+
+	// unless it's the only node, then it's kept as is
+	q.ZDBNodes = []uint32{10}
+	q.ZDBs = zdbs
+	q.ZDBs[0].Status = workloads.BackendStatusDown
+	assert.Equal(t, q.ZDBs[0], q.assignZDBs()[0])
+}
+
+func TestQSFSReplaceDownZDB(t *testing.T) {
+	st := state.NewState()
+	cl := &apiClient{state: &st}
+	var assigned []QSFSZDB
+	deploy := func(ctx context.Context, d *schema.ResourceData, i interface{}) diag.Diagnostics {
+		q, err := NewQSFSDeployer(d, cl)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		assigned = q.assignZDBs()
+		q.ZDBs = assigned
+		q.ID = "qsfs"
+		return diag.FromErr(q.Marshal(d))
+	}
+	noop := func(ctx context.Context, d *schema.ResourceData, i interface{}) diag.Diagnostics { return nil }
+	r := &schema.Resource{
+		Schema:        resourceQSFS().Schema,
+		CustomizeDiff: resourceQSFSCustomizeDiff,
+		CreateContext: deploy,
+		UpdateContext: deploy,
+		ReadContext:   noop,
+		DeleteContext: noop,
+	}
+	cfg := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":           "q",
+		"node":           1,
+		"zdb_nodes":      []interface{}{10, 20},
+		"meta_shards":    1,
+		"data_shards":    2,
+		"minimal_shards": 1,
+		"zdb_password":   "pass",
+		"cache":          1024,
+		"encryption_key": strings.Repeat("0", 64),
+	})
+	ctx := context.Background()
+	diff, err := r.Diff(ctx, nil, cfg, nil)
+	assert.NoError(t, err)
+	s, diags := r.Apply(ctx, nil, diff, nil)
+	assert.False(t, diags.HasError())
+	created := assigned
+	assert.Equal(t, []QSFSZDB{
+		{Name: "qmeta0", Node: 10, Mode: qsfsMetaMode},
+		{Name: "qdata0", Node: 20, Mode: qsfsDataMode},
+		{Name: "qdata1", Node: 10, Mode: qsfsDataMode},
+	}, created)
+
+	// nothing is planned while the zdbs are up
+	s.Attributes["zdbs.0.status"] = workloads.BackendStatusUp
+	diff, err = r.Diff(ctx, s, cfg, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, diff)
+
+	// the down zdb is moved to the other node and the rest are kept
+	s.Attributes["zdbs.0.status"] = workloads.BackendStatusDown
+	diff, err = r.Diff(ctx, s, cfg, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, diff)
+	_, diags = r.Apply(ctx, s, diff, nil)
+	assert.False(t, diags.HasError())
+	assert.Equal(t, []QSFSZDB{
+		{Name: "qmeta0", Node: 20, Mode: qsfsMetaMode},
+		created[1],
+		created[2],
+	}, assigned)
+
+	// nothing is planned if there's no other node to replace the down zdb on
+	s.Attributes["zdb_nodes.#"] = "1"
+	delete(s.Attributes, "zdb_nodes.1")
+	cfg.Config["zdb_nodes"] = []interface{}{10}
+	diff, err = r.Diff(ctx, s, cfg, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, diff)
+}
+
+func TestDownZDBs(t *testing.T) {
+	zdbs := []interface{}{
+		map[string]interface{}{"name": "qmeta0", "node": 10, "status": workloads.BackendStatusDown},
+		map[string]interface{}{"name": "qdata0", "node": 20, "status": workloads.BackendStatusDown},
+		map[string]interface{}{"name": "qdata1", "node": 10, "status": workloads.BackendStatusUp},
+	}
+	replaceable, stuck := downZDBs(zdbs, []interface{}{10, 20})
+	assert.Equal(t, []string{"qmeta0", "qdata0"}, replaceable)
+	assert.Empty(t, stuck)
+
+	replaceable, stuck = downZDBs(zdbs, []interface{}{10})
+	assert.Equal(t, []string{"qdata0"}, replaceable, "the removed node's zdb moves to node 10")
+	assert.Equal(t, []string{"qmeta0"}, stuck)
+}
+
+func TestZDBsStatus(t *testing.T) {
+	q := QSFSDeployer{}
+	q.QSFS.BackendsStatus = workloads.BackendsStatus{
+		{Address: "[300::1]:9900", Namespace: "ns1", Up: true},
+		{Address: "[300::1]:9900", Namespace: "ns2", Up: false},
+	}
+	zdbs := []QSFSZDB{
+		{Name: "qmeta0", Namespace: "ns1", Address: "[300::1]:9900"},
+		{Name: "qdata0", Namespace: "ns2", Address: "[300::1]:9900"},
+		{Name: "qdata1", Namespace: "ns3", Address: "[300::2]:9900"},
+		{Name: "qdata2"},
+	}
+	q.zdbsStatus(zdbs)
+	assert.Equal(t, workloads.BackendStatusUp, zdbs[0].Status)
+	assert.Equal(t, workloads.BackendStatusDown, zdbs[1].Status)
+	assert.Equal(t, workloads.BackendStatusUnknown, zdbs[2].Status)
+	assert.Equal(t, workloads.BackendStatusDown, zdbs[3].Status)
+}
+
+func TestZDBAddress(t *testing.T) {
+	assert.Equal(t, "", zdbAddress(workloads.ZDB{Port: 9900}))
+	assert.Equal(t, "[2a10::1]:9900", zdbAddress(workloads.ZDB{IPs: []string{"2a10::1"}, Port: 9900}))
+	assert.Equal(t, "[300::1]:9900", zdbAddress(workloads.ZDB{IPs: []string{"2a10::1", "300::1"}, Port: 9900}))
+}
+
+func TestQSFSBackends(t *testing.T) {
+	q := QSFSDeployer{ZDBPassword: "pass"}
+	err := q.backends([]QSFSZDB{
+		{Name: "qmeta0", Mode: qsfsMetaMode, Namespace: "ns1", Address: "[300::1]:9900"},
+		{Name: "qdata0", Mode: qsfsDataMode, Namespace: "ns2", Address: "[300::2]:9900"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, workloads.Backends{{Address: "[300::1]:9900", Namespace: "ns1", Password: "pass"}}, q.QSFS.Metadata.Backends)
+	assert.Equal(t, workloads.Groups{{Backends: workloads.Backends{{Address: "[300::2]:9900", Namespace: "ns2", Password: "pass"}}}}, q.QSFS.Groups)
+
+	err = q.backends([]QSFSZDB{{Name: "qdata0", Node: 10, Mode: qsfsDataMode}})
+	assert.Error(t, err)
+}
+
+func TestQSFSLoadZDBs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	deployer := mock.NewMockDeployer(ctrl)
+	dl := workloads.NewDeployment(11)
+	zdb := workloads.ZDB{Name: "qdata0", Mode: qsfsDataMode, Size: 1}
+	zdbWl := zdb.GenerateZDBWorkload()
+	zdbWl.Result.State = gridtypes.StateOk
+	zdbWl.Result.Data = mustMarshal(zos.ZDBResult{
+		Namespace: "ns",
+		IPs:       []string{"300::1"},
+		Port:      9900,
+	})
+	dl.Workloads = append(dl.Workloads, zdbWl)
+
+	q := QSFSDeployer{
+		ZDBDeploymentID: map[uint32]uint64{10: 100, 20: 200},
+		zdbDeployer:     deployer,
+	}
+	deployer.EXPECT().
+		GetDeployments(gomock.Any(), nil, map[uint32]uint64{10: 100}).
+		Return(map[uint32]gridtypes.Deployment{10: dl}, nil)
+	deployer.EXPECT().
+		GetDeployments(gomock.Any(), nil, map[uint32]uint64{20: 200}).
+		Return(nil, errors.New("node is down"))
+	zdbs := q.loadZDBs(context.Background(), nil, []QSFSZDB{
+		{Name: "qdata0", Node: 10, Mode: qsfsDataMode},
+		{Name: "qdata1", Node: 20, Mode: qsfsDataMode, Namespace: "old", Address: "[300::2]:9900"},
+	})
+	assert.Equal(t, []QSFSZDB{
+		{Name: "qdata0", Node: 10, Mode: qsfsDataMode, Namespace: "ns", Address: "[300::1]:9900"},
+		{Name: "qdata1", Node: 20, Mode: qsfsDataMode, Namespace: "old", Address: "[300::2]:9900"},
+	}, zdbs)
+}
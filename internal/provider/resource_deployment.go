@@ -136,130 +136,7 @@ func resourceDeployment() *schema.Resource {
 					},
 				},
 			},
-			"vms": {
-				Type:     schema.TypeList,
-				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"name": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"flist": {
-							Type:        schema.TypeString,
-							Required:    true,
-							Description: "e.g. https://hub.grid.tf/omar0.3bot/omarelawady-ubuntu-20.04.flist",
-						},
-						"flist_checksum": {
-							Type:        schema.TypeString,
-							Optional:    true,
-							Description: "if present, the flist is rejected if it has a different hash. the flist hash can be found by append",
-						},
-						"publicip": {
-							Type:        schema.TypeBool,
-							Optional:    true,
-							Description: "true to enable public ip reservation",
-						},
-						"publicip6": {
-							Type:        schema.TypeBool,
-							Optional:    true,
-							Description: "true to enable public ipv6 reservation",
-						},
-						"computedip": {
-							Type:        schema.TypeString,
-							Computed:    true,
-							Description: "The reserved public ip",
-						},
-						"computedip6": {
-							Type:        schema.TypeString,
-							Computed:    true,
-							Description: "The reserved public ipv6",
-						},
-						"ip": {
-							Type:        schema.TypeString,
-							Optional:    true,
-							Computed:    true,
-							Description: "The private wg IP of the Zmachine",
-						},
-						"cpu": {
-							Type:        schema.TypeInt,
-							Optional:    true,
-							Default:     1,
-							Description: "Number of VCPUs",
-						},
-						"description": {
-							Type:     schema.TypeString,
-							Optional: true,
-							Default:  "",
-						},
-						"memory": {
-							Type:        schema.TypeInt,
-							Optional:    true,
-							Description: "Memory size",
-						},
-						"rootfs_size": {
-							Type:        schema.TypeInt,
-							Optional:    true,
-							Description: "Rootfs size in MB",
-						},
-						"entrypoint": {
-							Type:        schema.TypeString,
-							Optional:    true,
-							Description: "command to execute as the Zmachine init",
-						},
-						"mounts": {
-							Type:     schema.TypeList,
-							Optional: true,
-							Elem: &schema.Resource{
-								Schema: map[string]*schema.Schema{
-									"disk_name": {
-										Type:        schema.TypeString,
-										Required:    true,
-										Description: "Name of QSFS or Disk to mount",
-									},
-									"mount_point": {
-										Type:        schema.TypeString,
-										Required:    true,
-										Description: "Directory to mount the disk on inside the Zmachine",
-									},
-								},
-							},
-							Description: "Zmachine mounts, can reference QSFSs and Disks",
-						},
-						"env_vars": {
-							Type:        schema.TypeMap,
-							Optional:    true,
-							Elem:        &schema.Schema{Type: schema.TypeString},
-							Description: "Environment variables to pass to the zmachine",
-						},
-						"planetary": {
-							Type:        schema.TypeBool,
-							Optional:    true,
-							Default:     false,
-							Description: "Enable Yggdrasil allocation",
-						},
-						"corex": {
-							Type:        schema.TypeBool,
-							Optional:    true,
-							Default:     false,
-							Description: "Enable corex",
-						},
-						"ygg_ip": {
-							Type:        schema.TypeString,
-							Computed:    true,
-							Description: "Allocated Yggdrasil IP",
-						},
-						"zlogs": {
-							Type:        schema.TypeList,
-							Optional:    true,
-							Description: "Zlogs is a utility workload that allows you to stream `zmachine` logs to a remote location.",
-							Elem: &schema.Schema{
-								Type:        schema.TypeString,
-								Description: "Url of the remote machine receiving logs."},
-						},
-					},
-				},
-			},
+			"vms": vmsSchema(),
 			"qsfs": {
 				Type:     schema.TypeList,
 				Optional: true,
@@ -412,6 +289,30 @@ func resourceDeployment() *schema.Resource {
 							Computed:    true,
 							Description: "QSFS exposed metrics",
 						},
+						"backends_status": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "Status of the metadata backends then the groups backends as reported by the metrics endpoint",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"address": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Address of backend zdb",
+									},
+									"namespace": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "ZDB namespace",
+									},
+									"status": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "up, down or unknown if the metrics endpoint isn't reachable",
+									},
+								},
+							},
+						},
 					},
 				},
 			},
@@ -464,3 +365,131 @@ func resourceDeploymentDelete(ctx context.Context, sub subi.SubstrateExt, d *sch
 
 	return &deployer, deployer.Cancel(ctx, sub)
 }
+
+// vmsSchema is the schema of the vms of a deployment
+func vmsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"flist": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "e.g. https://hub.grid.tf/omar0.3bot/omarelawady-ubuntu-20.04.flist",
+				},
+				"flist_checksum": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "if present, the flist is rejected if it has a different hash. the flist hash can be found by append",
+				},
+				"publicip": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Description: "true to enable public ip reservation",
+				},
+				"publicip6": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Description: "true to enable public ipv6 reservation",
+				},
+				"computedip": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "The reserved public ip",
+				},
+				"computedip6": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "The reserved public ipv6",
+				},
+				"ip": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Computed:    true,
+					Description: "The private wg IP of the Zmachine",
+				},
+				"cpu": {
+					Type:        schema.TypeInt,
+					Optional:    true,
+					Default:     1,
+					Description: "Number of VCPUs",
+				},
+				"description": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+				"memory": {
+					Type:        schema.TypeInt,
+					Optional:    true,
+					Description: "Memory size",
+				},
+				"rootfs_size": {
+					Type:        schema.TypeInt,
+					Optional:    true,
+					Description: "Rootfs size in MB",
+				},
+				"entrypoint": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "command to execute as the Zmachine init",
+				},
+				"mounts": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"disk_name": {
+								Type:        schema.TypeString,
+								Required:    true,
+								Description: "Name of QSFS or Disk to mount",
+							},
+							"mount_point": {
+								Type:        schema.TypeString,
+								Required:    true,
+								Description: "Directory to mount the disk on inside the Zmachine",
+							},
+						},
+					},
+					Description: "Zmachine mounts, can reference QSFSs and Disks",
+				},
+				"env_vars": {
+					Type:        schema.TypeMap,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "Environment variables to pass to the zmachine",
+				},
+				"planetary": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Enable Yggdrasil allocation",
+				},
+				"corex": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Enable corex",
+				},
+				"ygg_ip": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "Allocated Yggdrasil IP",
+				},
+				"zlogs": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Description: "Zlogs is a utility workload that allows you to stream `zmachine` logs to a remote location.",
+					Elem: &schema.Schema{
+						Type:        schema.TypeString,
+						Description: "Url of the remote machine receiving logs."},
+				},
+			},
+		},
+	}
+}
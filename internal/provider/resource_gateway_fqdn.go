@@ -18,6 +18,7 @@ func resourceGatewayFQDNProxy() *schema.Resource {
 		ReadContext:   ResourceReadFunc(resourceGatewayFQDNRead),
 		UpdateContext: ResourceFunc(resourceGatewayFQDNUpdate),
 		DeleteContext: ResourceFunc(resourceGatewayFQDNDelete),
+		CustomizeDiff: gatewayNodeCustomizeDiff(),
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -40,8 +41,19 @@ func resourceGatewayFQDNProxy() *schema.Resource {
 			},
 			"node": {
 				Type:        schema.TypeInt,
-				Required:    true,
-				Description: "The gateway's node id",
+				Optional:    true,
+				Computed:    true,
+				Description: "The gateway's node id, if omitted an up node with a domain is picked and kept until it becomes unavailable. Changing it deploys the gateway on the new node before removing it from the old one",
+			},
+			"farm": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Farm name to pick the gateway node from if node is omitted",
+			},
+			"country": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Country to pick the gateway node from if node is omitted",
 			},
 			"fqdn": {
 				Type:        schema.TypeString,
@@ -60,7 +72,56 @@ func resourceGatewayFQDNProxy() *schema.Resource {
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
-				Description: "The backends of the gateway proxy (in the format (http|https)://ip:port), with tls_passthrough the scheme must be https",
+				Description: "The backends of the gateway proxy in the format http://ip[:port], with tls_passthrough they must be in the format ip:port",
+			},
+			"dns": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Manage the A/AAAA records of the fqdn pointing to the gateway node public ips, they're created before deploying and removed on delete. Removing this block leaves the records as is",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"provider": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     dnsProviderRFC2136,
+							Description: "The dns provider managing the records, only rfc2136 (dynamic updates) is supported",
+						},
+						"server": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The dns server accepting the updates in the format host:port",
+						},
+						"zone": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The zone containing the fqdn",
+						},
+						"ttl": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Default:     300,
+							Description: "TTL of the records in seconds, when the gateway moves to another node the old one is kept for this long after the records are updated",
+						},
+						"tsig_key_name": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Name of the tsig key signing the updates and the server responses, the updates are unsigned if omitted",
+						},
+						"tsig_algorithm": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "hmac-sha256",
+							Description: "Algorithm of the tsig key: hmac-sha1, hmac-sha256 or hmac-sha512",
+						},
+						"tsig_secret": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Base64 encoded secret of the tsig key",
+						},
+					},
+				},
 			},
 			"node_deployment_id": {
 				Type:        schema.TypeMap,
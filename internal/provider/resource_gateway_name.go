@@ -18,6 +18,7 @@ func resourceGatewayNameProxy() *schema.Resource {
 		ReadContext:   ResourceReadFunc(resourceGatewayNameRead),
 		UpdateContext: ResourceFunc(resourceGatewayNameUpdate),
 		DeleteContext: ResourceFunc(resourceGatewayNameDelete),
+		CustomizeDiff: gatewayNodeCustomizeDiff("fqdn"),
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -38,8 +39,19 @@ func resourceGatewayNameProxy() *schema.Resource {
 			},
 			"node": {
 				Type:        schema.TypeInt,
-				Required:    true,
-				Description: "The gateway's node id",
+				Optional:    true,
+				Computed:    true,
+				Description: "The gateway's node id, if omitted an up node with a domain is picked and kept until it becomes unavailable. Changing it deploys the gateway on the new node before removing it from the old one",
+			},
+			"farm": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Farm name to pick the gateway node from if node is omitted",
+			},
+			"country": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Country to pick the gateway node from if node is omitted",
 			},
 			"fqdn": {
 				Type:        schema.TypeString,
@@ -58,7 +70,7 @@ func resourceGatewayNameProxy() *schema.Resource {
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
-				Description: "The backends of the gateway proxy (in the format (http|https)://ip:port), with tls_passthrough the scheme must be https",
+				Description: "The backends of the gateway proxy in the format http://ip[:port], with tls_passthrough they must be in the format ip:port",
 			},
 			"node_deployment_id": {
 				Type:        schema.TypeMap,
@@ -66,10 +78,15 @@ func resourceGatewayNameProxy() *schema.Resource {
 				Elem:        &schema.Schema{Type: schema.TypeInt},
 				Description: "Mapping from each node to its deployment id",
 			},
+			"name_contract": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "The contract_id of a grid_name_contract reserving the name, the gateway neither creates nor cancels the name contract if set",
+			},
 			"name_contract_id": {
 				Type:        schema.TypeInt,
 				Computed:    true,
-				Description: "The id of the name contract",
+				Description: "The id of the name contract used by the gateway",
 			},
 		},
 	}
@@ -16,12 +16,14 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
 	client "github.com/threefoldtech/terraform-provider-grid/internal/node"
+	"github.com/threefoldtech/terraform-provider-grid/internal/provider/scheduler"
 	"github.com/threefoldtech/terraform-provider-grid/pkg/deployer"
 	"github.com/threefoldtech/terraform-provider-grid/pkg/state"
 	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
 	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
 	"github.com/threefoldtech/zos/pkg/gridtypes"
 	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+	"golang.org/x/crypto/ssh"
 )
 
 func resourceKubernetes() *schema.Resource {
@@ -68,6 +70,46 @@ func resourceKubernetes() *schema.Resource {
 				Required:    true,
 				Description: "The cluster secret token",
 			},
+			"ssh_private_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "SSH private key matching ssh_key used to fetch the kubeconfig from the master, the ssh agent is used if not set",
+			},
+			"ssh_host_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Public host key of the master in authorized_keys format (e.g. ssh-ed25519 AAAA...) checked when connecting to it over ssh. If not set, the key of the first connection is trusted and stored, and it's reset when the master is moved to another node or renamed",
+			},
+			"rolling_update": {
+				Type:        schema.TypeList,
+				MaxItems:    1,
+				Optional:    true,
+				Description: "Update an existing cluster gradually: the master's node first then the rest of the nodes in batches, aborting on the first failure",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"batch_size": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Default:     1,
+							Description: "Number of nodes updated at once after the master's node, all k8s nodes on the same node are updated together",
+						},
+						"readiness_check": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Wait for the updated k8s nodes to be ready before the next batch, checked from the master over ssh (see ssh_private_key)",
+						},
+					},
+				},
+			},
+			"kubeconfig": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The cluster kubeconfig with the server pointing to the master's public ip, planetary ip or private ip in that order",
+			},
 			"nodes_ip_range": {
 				Type:        schema.TypeMap,
 				Computed:    true,
@@ -79,7 +121,7 @@ func resourceKubernetes() *schema.Resource {
 				Type:     schema.TypeList,
 				Required: true,
 				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
+					Schema: withK8sNodeCustomization(map[string]*schema.Schema{
 						"name": {
 							Type:        schema.TypeString,
 							Required:    true,
@@ -151,14 +193,14 @@ func resourceKubernetes() *schema.Resource {
 							Computed:    true,
 							Description: "Allocated Yggdrasil IP",
 						},
-					},
+					}),
 				},
 			},
 			"workers": {
 				Type:     schema.TypeList,
 				Optional: true,
 				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
+					Schema: withK8sNodeCustomization(map[string]*schema.Schema{
 						"name": {
 							Type:     schema.TypeString,
 							Required: true,
@@ -229,7 +271,121 @@ func resourceKubernetes() *schema.Resource {
 							Computed:    true,
 							Description: "Allocated Yggdrasil IP",
 						},
-					},
+					}),
+				},
+			},
+			"node_pools": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Pools of identical workers whose nodes are picked by the provider among the network nodes, add nodes to the network to widen the choice",
+				Elem: &schema.Resource{
+					Schema: withK8sNodeCustomization(map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Pool name, its workers are named <name><index>",
+						},
+						"count": {
+							Type:        schema.TypeInt,
+							Required:    true,
+							Description: "Number of workers in the pool",
+						},
+						"flist": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "https://hub.grid.tf/tf-official-apps/threefoldtech-k3s-latest.flist",
+						},
+						"flist_checksum": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "if present, the flist is rejected if it has a different hash. the flist hash can be found by append",
+						},
+						"disk_size": {
+							Type:        schema.TypeInt,
+							Required:    true,
+							Description: "Data disk size in GBs",
+						},
+						"cpu": {
+							Type:        schema.TypeInt,
+							Required:    true,
+							Description: "Number of VCPUs",
+						},
+						"memory": {
+							Type:        schema.TypeInt,
+							Required:    true,
+							Description: "Memory size",
+						},
+						"publicip": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "true to enable public ip reservation",
+						},
+						"publicip6": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "true to enable public ipv6 reservation",
+						},
+						"planetary": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Enable Yggdrasil allocation",
+						},
+						"farm": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Pick only nodes from the farm with this name",
+						},
+						"certified": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "Pick only certified nodes",
+						},
+						"ipv4": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "Pick only nodes with public config containing ipv4",
+						},
+						"nodes": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "The pool workers",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"name": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Worker name",
+									},
+									"node": {
+										Type:        schema.TypeInt,
+										Computed:    true,
+										Description: "Node ID",
+									},
+									"ip": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The private IP (computed from nodes_ip_range)",
+									},
+									"computedip": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The reserved public ip",
+									},
+									"computedip6": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The reserved public ipv6",
+									},
+									"ygg_ip": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Allocated Yggdrasil IP",
+									},
+								},
+							},
+						},
+					}),
 				},
 			},
 		},
@@ -251,20 +407,48 @@ type K8sNodeData struct {
 	IP            string
 	CPU           int
 	Memory        int
+
+	// Pool is the name of the node pool the worker belongs to, empty for workers defined explicitly
+	Pool string
+
+	K8sNodeCustomization
+}
+
+// K8sNodePool is a group of identical workers scheduled by the provider
+type K8sNodePool struct {
+	Name          string
+	Count         int
+	DiskSize      int
+	PublicIP      bool
+	PublicIP6     bool
+	Planetary     bool
+	Flist         string
+	FlistChecksum string
+	CPU           int
+	Memory        int
+	Farm          string
+	Certified     bool
+	IPv4          bool
+
+	K8sNodeCustomization
 }
 
 type K8sDeployer struct {
 	Master           *K8sNodeData
 	Workers          []K8sNodeData
+	NodePools        []K8sNodePool
 	NodesIPRange     map[uint32]gridtypes.IPNet
 	Token            string
 	SSHKey           string
+	SSHPrivateKey    string
+	SSHHostKey       string
 	NetworkName      string
 	NodeDeploymentID map[uint32]uint64
+	RollingUpdate    *K8sRollingUpdate
 
 	APIClient *apiClient
 
-	NodeUsedIPs map[uint32][]byte
+	NodeUsedIPs map[uint32][]uint32
 	ncPool      *client.NodeClientPool
 	d           *schema.ResourceData
 	deployer    deployer.Deployer
@@ -286,37 +470,126 @@ func NewK8sNodeData(m map[string]interface{}) K8sNodeData {
 		IP:            m["ip"].(string),
 		CPU:           m["cpu"].(int),
 		Memory:        m["memory"].(int),
+
+		K8sNodeCustomization: NewK8sNodeCustomization(m),
 	}
 }
 
-func NewK8sNodeDataFromWorkload(w gridtypes.Workload, nodeID uint32, diskSize int, computedIP string, computedIP6 string) (K8sNodeData, error) {
-	var k K8sNodeData
-	data, err := w.WorkloadData()
-	if err != nil {
-		return k, err
+func NewK8sNodePool(m map[string]interface{}) K8sNodePool {
+	return K8sNodePool{
+		Name:          m["name"].(string),
+		Count:         m["count"].(int),
+		DiskSize:      m["disk_size"].(int),
+		PublicIP:      m["publicip"].(bool),
+		PublicIP6:     m["publicip6"].(bool),
+		Planetary:     m["planetary"].(bool),
+		Flist:         m["flist"].(string),
+		FlistChecksum: m["flist_checksum"].(string),
+		CPU:           m["cpu"].(int),
+		Memory:        m["memory"].(int),
+		Farm:          m["farm"].(string),
+		Certified:     m["certified"].(bool),
+		IPv4:          m["ipv4"].(bool),
+
+		K8sNodeCustomization: NewK8sNodeCustomization(m),
+	}
+}
+
+// nodeName returns the name of the pool worker with the given index
+func (p *K8sNodePool) nodeName(idx int) string {
+	return fmt.Sprintf("%s%d", p.Name, idx)
+}
+
+// newNode returns a pool worker with the pool's specs
+func (p *K8sNodePool) newNode(idx int, nodeID uint32) K8sNodeData {
+	return K8sNodeData{
+		Name:          p.nodeName(idx),
+		Node:          nodeID,
+		DiskSize:      p.DiskSize,
+		PublicIP:      p.PublicIP,
+		PublicIP6:     p.PublicIP6,
+		Planetary:     p.Planetary,
+		Flist:         p.Flist,
+		FlistChecksum: p.FlistChecksum,
+		CPU:           p.CPU,
+		Memory:        p.Memory,
+		Pool:          p.Name,
+
+		K8sNodeCustomization: p.K8sNodeCustomization,
+	}
+}
+
+func (p *K8sNodePool) Dictify(nodes []interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+	res["name"] = p.Name
+	res["count"] = p.Count
+	res["disk_size"] = p.DiskSize
+	res["publicip"] = p.PublicIP
+	res["publicip6"] = p.PublicIP6
+	res["planetary"] = p.Planetary
+	res["flist"] = p.Flist
+	res["flist_checksum"] = p.FlistChecksum
+	res["cpu"] = p.CPU
+	res["memory"] = p.Memory
+	res["farm"] = p.Farm
+	res["certified"] = p.Certified
+	res["ipv4"] = p.IPv4
+	res["nodes"] = nodes
+	p.dictify(res)
+	return res
+}
+
+// poolWorkers returns the already scheduled workers of the node pools, pools scaled down lose their workers with the highest indices
+func poolWorkers(d *schema.ResourceData, pools []K8sNodePool) []K8sNodeData {
+	// previously scheduled workers are read from the old state to survive reordering the pools
+	scheduled := make(map[string]map[string]interface{})
+	before, _ := d.GetChange("node_pools")
+	for _, p := range before.([]interface{}) {
+		for _, n := range p.(map[string]interface{})["nodes"].([]interface{}) {
+			node := n.(map[string]interface{})
+			scheduled[node["name"].(string)] = node
+		}
 	}
-	d := data.(*zos.ZMachine)
-	var result zos.ZMachineResult
-	err = w.Result.Unmarshal(&result)
+	workers := make([]K8sNodeData, 0)
+	for _, p := range pools {
+		for idx := 0; idx < p.Count; idx++ {
+			node, ok := scheduled[p.nodeName(idx)]
+			if !ok || node["node"].(int) == 0 {
+				continue
+			}
+			worker := p.newNode(idx, uint32(node["node"].(int)))
+			worker.IP = node["ip"].(string)
+			worker.ComputedIP = node["computedip"].(string)
+			worker.ComputedIP6 = node["computedip6"].(string)
+			worker.YggIP = node["ygg_ip"].(string)
+			workers = append(workers, worker)
+		}
+	}
+	return workers
+}
+
+func NewK8sNodeDataFromWorkload(w gridtypes.Workload, dl *gridtypes.Deployment, nodeID uint32, computedIP string, computedIP6 string, diskSizes map[string]int) (K8sNodeData, error) {
+	vm, err := workloads.NewVMFromWorkloads(&w, dl)
 	if err != nil {
-		return k, err
+		return K8sNodeData{}, err
 	}
-	k = K8sNodeData{
-		Name:        string(w.Name),
+	return K8sNodeData{
+		Name:        vm.Name,
 		Node:        nodeID,
-		DiskSize:    diskSize,
+		DiskSize:    diskSizes[fmt.Sprintf("%sdisk", vm.Name)],
 		PublicIP:    computedIP != "",
 		PublicIP6:   computedIP6 != "",
-		Planetary:   result.YggIP != "",
-		Flist:       d.FList,
+		Planetary:   vm.Planetary,
+		Flist:       vm.Flist,
 		ComputedIP:  computedIP,
 		ComputedIP6: computedIP6,
-		YggIP:       result.YggIP,
-		IP:          d.Network.Interfaces[0].IP.String(),
-		CPU:         int(d.ComputeCapacity.CPU),
-		Memory:      int(d.ComputeCapacity.Memory / gridtypes.Megabyte),
-	}
-	return k, nil
+		YggIP:       vm.YggIP,
+		IP:          vm.IP,
+		CPU:         vm.CPU,
+		Memory:      vm.Memory,
+
+		K8sNodeCustomization: NewK8sNodeCustomizationFromVM(&vm, diskSizes),
+	}, nil
 }
 
 func NewK8sDeployer(d *schema.ResourceData, apiClient *apiClient) (K8sDeployer, error) {
@@ -326,20 +599,15 @@ func NewK8sDeployer(d *schema.ResourceData, apiClient *apiClient) (K8sDeployer,
 
 	master := NewK8sNodeData(d.Get("master").([]interface{})[0].(map[string]interface{}))
 	workers := make([]K8sNodeData, 0)
-	usedIPs := make(map[uint32][]byte)
-
-	if master.IP != "" {
-		usedIPs[master.Node] = append(usedIPs[master.Node], net.ParseIP(master.IP)[3])
-	}
-	usedIPs[master.Node] = append(usedIPs[master.Node], network.GetNodeIPsList(master.Node)...)
 	for _, w := range d.Get("workers").([]interface{}) {
 		data := NewK8sNodeData(w.(map[string]interface{}))
 		workers = append(workers, data)
-		if data.IP != "" {
-			usedIPs[data.Node] = append(usedIPs[data.Node], net.ParseIP(data.IP)[3])
-			usedIPs[data.Node] = append(usedIPs[data.Node], network.GetNodeIPsList(data.Node)...)
-		}
 	}
+	pools := make([]K8sNodePool, 0)
+	for _, p := range d.Get("node_pools").([]interface{}) {
+		pools = append(pools, NewK8sNodePool(p.(map[string]interface{})))
+	}
+	workers = append(workers, poolWorkers(d, pools)...)
 	nodesIPRange := make(map[uint32]gridtypes.IPNet)
 	var err error
 	nodesIPRange[master.Node], err = gridtypes.ParseIPNet(network.GetNodeSubnet(master.Node))
@@ -352,6 +620,17 @@ func NewK8sDeployer(d *schema.ResourceData, apiClient *apiClient) (K8sDeployer,
 			return K8sDeployer{}, errors.Wrapf(err, "couldn't parse worker node (%d) ip range", worker.Node)
 		}
 	}
+	usedIPs := make(map[uint32][]uint32)
+	if master.IP != "" {
+		usedIPs[master.Node] = append(usedIPs[master.Node], state.HostID(nodesIPRange[master.Node].IPNet, net.ParseIP(master.IP)))
+	}
+	usedIPs[master.Node] = append(usedIPs[master.Node], network.GetNodeIPsList(master.Node)...)
+	for _, w := range workers {
+		if w.IP != "" {
+			usedIPs[w.Node] = append(usedIPs[w.Node], state.HostID(nodesIPRange[w.Node].IPNet, net.ParseIP(w.IP)))
+			usedIPs[w.Node] = append(usedIPs[w.Node], network.GetNodeIPsList(w.Node)...)
+		}
+	}
 	nodeDeploymentIDIf := d.Get("node_deployment_id").(map[string]interface{})
 	nodeDeploymentID := make(map[uint32]uint64)
 	for node, id := range nodeDeploymentIDIf {
@@ -363,6 +642,11 @@ func NewK8sDeployer(d *schema.ResourceData, apiClient *apiClient) (K8sDeployer,
 		nodeDeploymentID[uint32(nodeInt)] = deploymentID
 	}
 
+	var rollingUpdate *K8sRollingUpdate
+	if r := d.Get("rolling_update").([]interface{}); len(r) != 0 && r[0] != nil {
+		rollingUpdate = NewK8sRollingUpdate(r[0].(map[string]interface{}))
+	}
+
 	pool := client.NewNodeClientPool(apiClient.rmb)
 	deploymentData := DeploymentData{
 		Name:        d.Get("name").(string),
@@ -376,10 +660,14 @@ func NewK8sDeployer(d *schema.ResourceData, apiClient *apiClient) (K8sDeployer,
 	deployer := K8sDeployer{
 		Master:           &master,
 		Workers:          workers,
+		NodePools:        pools,
 		Token:            d.Get("token").(string),
 		SSHKey:           d.Get("ssh_key").(string),
+		SSHPrivateKey:    d.Get("ssh_private_key").(string),
+		SSHHostKey:       sshHostKey(d),
 		NetworkName:      d.Get("network_name").(string),
 		NodeDeploymentID: nodeDeploymentID,
+		RollingUpdate:    rollingUpdate,
 		NodeUsedIPs:      usedIPs,
 		NodesIPRange:     nodesIPRange,
 		APIClient:        apiClient,
@@ -405,9 +693,86 @@ func (k *K8sNodeData) Dictify() map[string]interface{} {
 	res["ip"] = k.IP
 	res["cpu"] = k.CPU
 	res["memory"] = k.Memory
+	k.dictify(res)
+	return res
+}
+
+// dictifyPoolNode returns the computed attributes of a node pool worker
+func (k *K8sNodeData) dictifyPoolNode() map[string]interface{} {
+	res := make(map[string]interface{})
+	res["name"] = k.Name
+	res["node"] = int(k.Node)
+	res["ip"] = k.IP
+	res["computedip"] = k.ComputedIP
+	res["computedip6"] = k.ComputedIP6
+	res["ygg_ip"] = k.YggIP
 	return res
 }
 
+// scheduleNodePools picks nodes for the node pools workers that aren't scheduled yet among the network nodes
+func (k *K8sDeployer) scheduleNodePools() error {
+	if len(k.NodePools) == 0 {
+		return nil
+	}
+	network := k.APIClient.state.GetNetworkState().GetNetwork(k.NetworkName)
+	networkNodes := make([]uint32, 0)
+	for node := range network.GetNodeDeploymentIDs() {
+		networkNodes = append(networkNodes, node)
+	}
+	if len(networkNodes) == 0 {
+		return fmt.Errorf("network %s has no nodes to schedule the node pools workers on", k.NetworkName)
+	}
+
+	scheduled := make(map[string]K8sNodeData)
+	workers := make([]K8sNodeData, 0)
+	for _, w := range k.Workers {
+		if w.Pool == "" {
+			workers = append(workers, w)
+		} else {
+			scheduled[w.Name] = w
+		}
+	}
+	sched := scheduler.NewScheduler(k.APIClient.grid_client, uint64(k.APIClient.twin_id))
+	for _, p := range k.NodePools {
+		var publicIPs uint64
+		if p.PublicIP {
+			publicIPs = 1
+		}
+		for idx := 0; idx < p.Count; idx++ {
+			if w, ok := scheduled[p.nodeName(idx)]; ok {
+				workers = append(workers, w)
+				continue
+			}
+			node, err := sched.Schedule(&scheduler.Request{
+				Name:      p.nodeName(idx),
+				Farm:      p.Farm,
+				HasIPv4:   p.IPv4,
+				Certified: p.Certified,
+				PublicIPs: publicIPs,
+				Nodes:     networkNodes,
+				Capacity: scheduler.Capacity{
+					CRU: uint64(p.CPU),
+					MRU: uint64(p.Memory) * uint64(gridtypes.Megabyte),
+					SRU: uint64(p.DiskSize) * uint64(gridtypes.Gigabyte),
+				},
+			})
+			if err != nil {
+				return errors.Wrapf(err, "couldn't schedule worker %s of node pool %s", p.nodeName(idx), p.Name)
+			}
+			if _, ok := k.NodesIPRange[node]; !ok {
+				k.NodesIPRange[node], err = gridtypes.ParseIPNet(network.GetNodeSubnet(node))
+				if err != nil {
+					return errors.Wrapf(err, "couldn't parse node (%d) ip range", node)
+				}
+				k.NodeUsedIPs[node] = append(k.NodeUsedIPs[node], network.GetNodeIPsList(node)...)
+			}
+			workers = append(workers, p.newNode(idx, node))
+		}
+	}
+	k.Workers = workers
+	return nil
+}
+
 // invalidateBrokenAttributes removes outdated attrs and deleted contracts
 func (k *K8sDeployer) invalidateBrokenAttributes(sub subi.SubstrateExt) error {
 	newWorkers := make([]K8sNodeData, 0)
@@ -452,9 +817,22 @@ func (d *K8sDeployer) retainChecksums(workers []interface{}, master interface{})
 
 func (k *K8sDeployer) storeState(d *schema.ResourceData, cl *apiClient) (errors error) {
 	workers := make([]interface{}, 0)
+	poolNodes := make(map[string][]interface{})
 	for _, w := range k.Workers {
+		if w.Pool != "" {
+			poolNodes[w.Pool] = append(poolNodes[w.Pool], w.dictifyPoolNode())
+			continue
+		}
 		workers = append(workers, w.Dictify())
 	}
+	pools := make([]interface{}, 0)
+	for _, p := range k.NodePools {
+		nodes := poolNodes[p.Name]
+		if nodes == nil {
+			nodes = make([]interface{}, 0)
+		}
+		pools = append(pools, p.Dictify(nodes))
+	}
 	nodeDeploymentID := make(map[string]interface{})
 	for node, id := range k.NodeDeploymentID {
 		nodeDeploymentID[fmt.Sprintf("%d", node)] = int(id)
@@ -478,6 +856,11 @@ func (k *K8sDeployer) storeState(d *schema.ResourceData, cl *apiClient) (errors
 		errors = multierror.Append(errors, err)
 	}
 
+	err = d.Set("node_pools", pools)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
 	err = d.Set("token", k.Token)
 	if err != nil {
 		errors = multierror.Append(errors, err)
@@ -488,6 +871,11 @@ func (k *K8sDeployer) storeState(d *schema.ResourceData, cl *apiClient) (errors
 		errors = multierror.Append(errors, err)
 	}
 
+	err = d.Set("ssh_host_key", k.SSHHostKey)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
 	err = d.Set("network_name", k.NetworkName)
 	if err != nil {
 		errors = multierror.Append(errors, err)
@@ -501,8 +889,8 @@ func (k *K8sDeployer) storeState(d *schema.ResourceData, cl *apiClient) (errors
 	return
 }
 
-func (k *K8sDeployer) updateNetworkState(d *schema.ResourceData, state state.StateI) {
-	ns := state.GetNetworkState()
+func (k *K8sDeployer) updateNetworkState(d *schema.ResourceData, st state.StateI) {
+	ns := st.GetNetworkState()
 	network := ns.GetNetwork(k.NetworkName)
 	before, _ := d.GetChange("node_deployment_id")
 	for node, deploymentID := range before.(map[string]interface{}) {
@@ -526,7 +914,7 @@ func (k *K8sDeployer) updateNetworkState(d *schema.ResourceData, state state.Sta
 	if masterIP == nil {
 		log.Printf("couldn't parse master ip")
 	} else {
-		masterNodeIPs = append(masterNodeIPs, masterIP.To4()[3])
+		masterNodeIPs = append(masterNodeIPs, state.HostID(k.NodesIPRange[k.Master.Node].IPNet, masterIP))
 	}
 	network.SetDeploymentIPs(k.Master.Node, fmt.Sprint(k.NodeDeploymentID[k.Master.Node]), masterNodeIPs)
 	for _, worker := range k.Workers {
@@ -535,7 +923,7 @@ func (k *K8sDeployer) updateNetworkState(d *schema.ResourceData, state state.Sta
 		if workerIP == nil {
 			log.Printf("couldn't parse worker ip at node (%d)", worker.Node)
 		} else {
-			workerNodeIPs = append(workerNodeIPs, workerIP.To4()[3])
+			workerNodeIPs = append(workerNodeIPs, state.HostID(k.NodesIPRange[worker.Node].IPNet, workerIP))
 		}
 		network.SetDeploymentIPs(worker.Node, fmt.Sprint(k.NodeDeploymentID[worker.Node]), workerNodeIPs)
 	}
@@ -634,6 +1022,44 @@ func (k *K8sDeployer) ValidateNames(ctx context.Context) error {
 	return nil
 }
 
+func (k *K8sDeployer) validateNodePools(ctx context.Context) error {
+	names := make(map[string]bool)
+	for _, p := range k.NodePools {
+		if names[p.Name] {
+			return fmt.Errorf("node pools must have unique names: %s occurred more than once", p.Name)
+		}
+		names[p.Name] = true
+		if p.Count < 0 {
+			return fmt.Errorf("node pool %s count can't be negative", p.Name)
+		}
+	}
+	return nil
+}
+
+func (k *K8sDeployer) validateNodesCustomization(ctx context.Context) error {
+	if err := k.Master.validate(k.Master.Name); err != nil {
+		return err
+	}
+	for _, w := range k.Workers {
+		if err := w.validate(w.Name); err != nil {
+			return err
+		}
+	}
+	for _, p := range k.NodePools {
+		if err := p.validate(fmt.Sprintf("node pool %s", p.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *K8sDeployer) validateRollingUpdate(ctx context.Context) error {
+	if k.RollingUpdate != nil && k.RollingUpdate.BatchSize < 1 {
+		return fmt.Errorf("rolling update batch size should be at least 1, found %d", k.RollingUpdate.BatchSize)
+	}
+	return nil
+}
+
 func (k *K8sDeployer) ValidateIPranges(ctx context.Context) error {
 
 	if _, ok := k.NodesIPRange[k.Master.Node]; !ok {
@@ -667,6 +1093,20 @@ func (k *K8sDeployer) Validate(ctx context.Context, sub subi.SubstrateExt) error
 	if err := validateAccountMoneyForExtrinsics(sub, k.APIClient.identity); err != nil {
 		return err
 	}
+	if k.SSHHostKey != "" {
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k.SSHHostKey)); err != nil {
+			return errors.Wrap(err, "couldn't parse ssh_host_key")
+		}
+	}
+	if err := k.validateNodePools(ctx); err != nil {
+		return err
+	}
+	if err := k.validateRollingUpdate(ctx); err != nil {
+		return err
+	}
+	if err := k.validateNodesCustomization(ctx); err != nil {
+		return err
+	}
 	if err := k.ValidateNames(ctx); err != nil {
 		return err
 	}
@@ -690,7 +1130,12 @@ func (k *K8sDeployer) Deploy(ctx context.Context, sub subi.SubstrateExt, d *sche
 	if err != nil {
 		return errors.Wrap(err, "couldn't generate deployments data")
 	}
-	currentDeployments, err := k.deployer.Deploy(ctx, sub, k.NodeDeploymentID, newDeployments)
+	var currentDeployments map[uint32]uint64
+	if k.RollingUpdate != nil && len(k.NodeDeploymentID) != 0 {
+		currentDeployments, err = k.rollingDeploy(ctx, sub, newDeployments)
+	} else {
+		currentDeployments, err = k.deployer.Deploy(ctx, sub, k.NodeDeploymentID, newDeployments)
+	}
 	if err := k.updateState(ctx, sub, currentDeployments, d, cl); err != nil {
 		log.Printf("error updating state: %s\n", err)
 	}
@@ -789,7 +1234,7 @@ func (k *K8sDeployer) updateState(ctx context.Context, sub subi.SubstrateExt, cu
 	for idx, w := range k.Workers {
 		workerIPName := fmt.Sprintf("%sip", w.Name)
 		k.Workers[idx].ComputedIP = publicIPs[workerIPName]
-		k.Workers[idx].ComputedIP = publicIP6s[workerIPName]
+		k.Workers[idx].ComputedIP6 = publicIP6s[workerIPName]
 		k.Workers[idx].IP = privateIPs[string(w.Name)]
 		k.Workers[idx].YggIP = yggIPs[string(w.Name)]
 	}
@@ -860,9 +1305,8 @@ func (k *K8sDeployer) updateFromRemote(ctx context.Context, sub subi.SubstrateEx
 		nodeDeploymentID[node] = dl.ContractID
 	}
 	k.NodeDeploymentID = nodeDeploymentID
-	// maps from workload name to (public ip, node id, disk size, actual workload)
+	// maps from workload name to (public ip, node id, actual workload)
 	workloadNodeID := make(map[string]uint32)
-	workloadDiskSize := make(map[string]int)
 	workloadComputedIP := make(map[string]string)
 	workloadComputedIP6 := make(map[string]string)
 	workloadObj := make(map[string]gridtypes.Workload)
@@ -898,8 +1342,6 @@ func (k *K8sDeployer) updateFromRemote(ctx context.Context, sub subi.SubstrateEx
 		for _, w := range dl.Workloads {
 			if w.Type == zos.ZMachineType {
 				publicIPKey := fmt.Sprintf("%sip", w.Name)
-				diskKey := fmt.Sprintf("%sdisk", w.Name)
-				workloadDiskSize[string(w.Name)] = diskSize[diskKey]
 				workloadComputedIP[string(w.Name)] = publicIPs[publicIPKey]
 				workloadComputedIP6[string(w.Name)] = publicIP6s[publicIPKey]
 			}
@@ -913,9 +1355,9 @@ func (k *K8sDeployer) updateFromRemote(ctx context.Context, sub subi.SubstrateEx
 		masterWorkload := workloadObj[k.Master.Name]
 		masterIP := workloadComputedIP[k.Master.Name]
 		masterIP6 := workloadComputedIP6[k.Master.Name]
-		masterDiskSize := workloadDiskSize[k.Master.Name]
+		masterDeployment := currentDeployments[masterNodeID]
 
-		m, err := NewK8sNodeDataFromWorkload(masterWorkload, masterNodeID, masterDiskSize, masterIP, masterIP6)
+		m, err := NewK8sNodeDataFromWorkload(masterWorkload, &masterDeployment, masterNodeID, masterIP, masterIP6, diskSize)
 		if err != nil {
 			return errors.Wrap(err, "failed to get master data from workload")
 		}
@@ -924,6 +1366,7 @@ func (k *K8sDeployer) updateFromRemote(ctx context.Context, sub subi.SubstrateEx
 	// update workers
 	workers := make([]K8sNodeData, 0)
 	for _, w := range k.Workers {
+		pool := w.Pool
 		workerNodeID, ok := workloadNodeID[w.Name]
 		if !ok {
 			// worker doesn't exist in any deployment, skip it
@@ -933,12 +1376,13 @@ func (k *K8sDeployer) updateFromRemote(ctx context.Context, sub subi.SubstrateEx
 		workerWorkload := workloadObj[w.Name]
 		workerIP := workloadComputedIP[w.Name]
 		workerIP6 := workloadComputedIP6[w.Name]
+		workerDeployment := currentDeployments[workerNodeID]
 
-		workerDiskSize := workloadDiskSize[w.Name]
-		w, err := NewK8sNodeDataFromWorkload(workerWorkload, workerNodeID, workerDiskSize, workerIP, workerIP6)
+		w, err := NewK8sNodeDataFromWorkload(workerWorkload, &workerDeployment, workerNodeID, workerIP, workerIP6, diskSize)
 		if err != nil {
 			return errors.Wrap(err, "failed to get worker data from workload")
 		}
+		w.Pool = pool
 		workers = append(workers, w)
 	}
 	// add missing workers (in case of failed deletions)
@@ -949,8 +1393,8 @@ func (k *K8sDeployer) updateFromRemote(ctx context.Context, sub subi.SubstrateEx
 		workerWorkload := workloadObj[name]
 		workerIP := workloadComputedIP[name]
 		workerIP6 := workloadComputedIP6[name]
-		workerDiskSize := workloadDiskSize[name]
-		w, err := NewK8sNodeDataFromWorkload(workerWorkload, workerNodeID, workerDiskSize, workerIP, workerIP6)
+		workerDeployment := currentDeployments[workerNodeID]
+		w, err := NewK8sNodeDataFromWorkload(workerWorkload, &workerDeployment, workerNodeID, workerIP, workerIP6, diskSize)
 		if err != nil {
 			return errors.Wrap(err, "failed to get worker data from workload")
 		}
@@ -981,11 +1425,6 @@ func (k *K8sNodeData) GenerateK8sWorkload(deployer *K8sDeployer, masterIP string
 		}),
 	}
 	K8sWorkloads = append(K8sWorkloads, diskWorkload)
-	publicIPName := ""
-	if k.PublicIP || k.PublicIP6 {
-		publicIPName = fmt.Sprintf("%sip", k.Name)
-		K8sWorkloads = append(K8sWorkloads, workloads.ConstructPublicIPWorkload(publicIPName, k.PublicIP, k.PublicIP6))
-	}
 	envVars := map[string]string{
 		"SSH_KEY":           deployer.SSHKey,
 		"K3S_TOKEN":         deployer.Token,
@@ -997,53 +1436,56 @@ func (k *K8sNodeData) GenerateK8sWorkload(deployer *K8sDeployer, masterIP string
 	if masterIP != "" {
 		envVars["K3S_URL"] = fmt.Sprintf("https://%s:6443", masterIP)
 	}
-	workload := gridtypes.Workload{
-		Version: 0,
-		Name:    gridtypes.Name(k.Name),
-		Type:    zos.ZMachineType,
-		Data: gridtypes.MustMarshal(zos.ZMachine{
-			FList: k.Flist,
-			Network: zos.MachineNetwork{
-				Interfaces: []zos.MachineInterface{
-					{
-						Network: gridtypes.Name(deployer.NetworkName),
-						IP:      net.ParseIP(k.IP),
-					},
-				},
-				PublicIP:  gridtypes.Name(publicIPName),
-				Planetary: k.Planetary,
-			},
-			ComputeCapacity: zos.MachineCapacity{
-				CPU:    uint8(k.CPU),
-				Memory: gridtypes.Unit(uint(k.Memory)) * gridtypes.Megabyte,
-			},
-			Entrypoint: "/sbin/zinit init",
-			Mounts: []zos.MachineMount{
-				{Name: gridtypes.Name(diskName), Mountpoint: "/mydisk"},
-			},
-			Env: envVars,
-		}),
-	}
-	K8sWorkloads = append(K8sWorkloads, workload)
+	vm := workloads.VM{
+		Name:        k.Name,
+		Flist:       k.Flist,
+		PublicIP:    k.PublicIP,
+		PublicIP6:   k.PublicIP6,
+		Planetary:   k.Planetary,
+		IP:          k.IP,
+		CPU:         k.CPU,
+		Memory:      k.Memory,
+		Entrypoint:  "/sbin/zinit init",
+		Mounts:      []workloads.Mount{{DiskName: diskName, MountPoint: "/mydisk"}},
+		EnvVars:     envVars,
+		NetworkName: deployer.NetworkName,
+	}
+	K8sWorkloads = append(K8sWorkloads, k.customizeVM(&vm)...)
+	K8sWorkloads = append(K8sWorkloads, vm.GenerateVMWorkload()...)
 
 	return K8sWorkloads
 }
 
 func (k *K8sDeployer) getK8sFreeIP(ipRange gridtypes.IPNet, nodeID uint32) (string, error) {
-	ip := ipRange.IP.To4()
-	if ip == nil {
+	if ipRange.IP.To4() == nil {
 		return "", fmt.Errorf("the provided ip range (%s) is not a valid ipv4", ipRange.String())
 	}
 
-	for i := 2; i < 255; i++ {
-		hostID := byte(i)
-		if !Contains(k.NodeUsedIPs[nodeID], hostID) {
-			k.NodeUsedIPs[nodeID] = append(k.NodeUsedIPs[nodeID], hostID)
-			ip[3] = hostID
-			return ip.String(), nil
-		}
+	hostID, err := state.NextFreeHostID(ipRange.IPNet, k.NodeUsedIPs[nodeID])
+	if err != nil {
+		return "", err
+	}
+	k.NodeUsedIPs[nodeID] = append(k.NodeUsedIPs[nodeID], hostID)
+	return state.HostIP(ipRange.IPNet, hostID).String(), nil
+}
+
+// storeKubeconfig fetches the kubeconfig from the master, failing to fetch it doesn't fail the deployment
+func (k *K8sDeployer) storeKubeconfig(ctx context.Context, d *schema.ResourceData) diag.Diagnostics {
+	kubeconfig, err := fetchKubeconfig(ctx, k.Master, k.SSHPrivateKey, k.hostKeyCallback())
+	if err := d.Set("ssh_host_key", k.SSHHostKey); err != nil {
+		return diag.FromErr(err)
+	}
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "Couldn't fetch the cluster kubeconfig",
+			Detail:   err.Error(),
+		}}
+	}
+	if err := d.Set("kubeconfig", kubeconfig); err != nil {
+		return diag.FromErr(err)
 	}
-	return "", errors.New("all ips are used")
+	return nil
 }
 
 func resourceK8sCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -1058,6 +1500,10 @@ func resourceK8sCreate(ctx context.Context, d *schema.ResourceData, meta interfa
 		return diag.FromErr(errors.Wrap(err, "couldn't load deployer data"))
 	}
 
+	if err := deployer.scheduleNodePools(); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't schedule node pools"))
+	}
+
 	if err := deployer.Validate(ctx, apiClient.substrateConn); err != nil {
 		return diag.FromErr(err)
 	}
@@ -1071,10 +1517,14 @@ func resourceK8sCreate(ctx context.Context, d *schema.ResourceData, meta interfa
 			return diag.FromErr(err)
 		}
 	}
+	deployed := err == nil
 	err = deployer.storeState(d, apiClient)
 	if err != nil {
 		diags = diag.FromErr(err)
 	}
+	if deployed {
+		diags = append(diags, deployer.storeKubeconfig(ctx, d)...)
+	}
 
 	d.SetId(uuid.New().String())
 	return diags
@@ -1092,6 +1542,10 @@ func resourceK8sUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 		return diag.FromErr(errors.Wrap(err, "couldn't load deployer data"))
 	}
 
+	if err := deployer.scheduleNodePools(); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't schedule node pools"))
+	}
+
 	if err := deployer.Validate(ctx, apiClient.substrateConn); err != nil {
 		return diag.FromErr(err)
 	}
@@ -1104,10 +1558,14 @@ func resourceK8sUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 	if err != nil {
 		diags = diag.FromErr(err)
 	}
+	deployed := err == nil
 	err = deployer.storeState(d, apiClient)
 	if err != nil {
 		diags = diag.FromErr(err)
 	}
+	if deployed && (d.HasChange("master") || d.Get("kubeconfig").(string) == "") {
+		diags = append(diags, deployer.storeKubeconfig(ctx, d)...)
+	}
 
 	return diags
 }
@@ -0,0 +1,39 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func k8sVMEnv(t *testing.T, wls []gridtypes.Workload) map[string]string {
+	for _, wl := range wls {
+		if wl.Type != zos.ZMachineType {
+			continue
+		}
+		data, err := wl.WorkloadData()
+		assert.NoError(t, err)
+		return data.(*zos.ZMachine).Env
+	}
+	t.Fatal("no vm workload generated")
+	return nil
+}
+
+func TestGenerateK8sWorkloadEnv(t *testing.T) {
+	k := K8sDeployer{
+		Master:      &K8sNodeData{Name: "m0", Node: 1, IP: "10.1.2.2"},
+		Workers:     []K8sNodeData{{Name: "w0", Node: 1}},
+		Token:       "token",
+		SSHKey:      "key",
+		NetworkName: "net",
+	}
+	env := k8sVMEnv(t, k.Master.GenerateK8sWorkload(&k, ""))
+	assert.Equal(t, "", env["K3S_URL"], "the master initializes the cluster")
+	assert.Equal(t, "m0", env["K3S_NODE_NAME"])
+
+	env = k8sVMEnv(t, k.Workers[0].GenerateK8sWorkload(&k, k.Master.IP))
+	assert.Equal(t, "https://10.1.2.2:6443", env["K3S_URL"])
+	assert.Equal(t, "w0", env["K3S_NODE_NAME"])
+}
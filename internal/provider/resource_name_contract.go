@@ -0,0 +1,66 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+)
+
+func resourceNameContract() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Resource for reserving a gateway name independently of the gateways using it, the name survives gateway node migrations and destroying the gateways.",
+
+		CreateContext: ResourceFunc(resourceNameContractCreate),
+		ReadContext:   ResourceReadFunc(resourceNameContractRead),
+		UpdateContext: ResourceFunc(resourceNameContractUpdate),
+		DeleteContext: ResourceFunc(resourceNameContractDelete),
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The reserved gateway name, if it's already reserved by the same twin the existing contract is adopted",
+			},
+			"keep_on_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Keep the name contract on the chain when the resource is destroyed, useful when the name is shared with other environments",
+			},
+			"adopted": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "True if the name was already reserved by the twin and its contract was adopted, adopted contracts are never canceled on destroy",
+			},
+			"contract_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The id of the name contract, to be used as name_contract of grid_name_proxy",
+			},
+		},
+	}
+}
+
+func resourceNameContractCreate(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	contract := NewNameContract(d, apiClient)
+	return &contract, contract.Deploy(ctx, sub)
+}
+
+func resourceNameContractUpdate(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	contract := NewNameContract(d, apiClient)
+	return &contract, contract.Deploy(ctx, sub)
+}
+
+func resourceNameContractRead(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	contract := NewNameContract(d, apiClient)
+	return &contract, nil
+}
+
+func resourceNameContractDelete(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	contract := NewNameContract(d, apiClient)
+	return &contract, contract.Cancel(ctx, sub)
+}
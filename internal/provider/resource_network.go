@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
+	"sort"
 	"strconv"
 
 	"github.com/google/uuid"
@@ -62,7 +64,14 @@ func resourceNetwork() *schema.Resource {
 			"ip_range": {
 				Type:        schema.TypeString,
 				Required:    true,
-				Description: "Network ip range",
+				Description: "Network ip range, must be a /16 range",
+			},
+			"node_subnet_prefix": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     state.DefaultNodeSubnetPrefix,
+				Description: "Prefix length of the subnet assigned to each node out of the network ip range, zos only supports 24. Changing it recreates the network",
 			},
 			"add_wg_access": {
 				Type:        schema.TypeBool,
@@ -103,6 +112,40 @@ func resourceNetwork() *schema.Resource {
 				Elem:        &schema.Schema{Type: schema.TypeInt},
 				Description: "Mapping from each node to its deployment id",
 			},
+			"nodes_info": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Wireguard configuration of each node in the network (including the public node)",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"node_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Node id",
+						},
+						"wg_public_key": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Wireguard public key of the node",
+						},
+						"wg_port": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Wireguard listen port of the node",
+						},
+						"endpoint": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Public ip the node is reached on by the other nodes, empty if the node is reached through the public node",
+						},
+						"through_public_node": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the node is hidden (has no public ip) and is reached through the public node",
+						},
+					},
+				},
+			},
 		},
 	}
 }
@@ -112,7 +155,9 @@ type NetworkDeployer struct {
 	Description string
 	Nodes       []uint32
 	IPRange     gridtypes.IPNet
-	AddWGAccess bool
+	// NodeSubnetPrefix is the prefix length of node subnets
+	NodeSubnetPrefix int
+	AddWGAccess      bool
 
 	AccessWGConfig   string
 	ExternalIP       *gridtypes.IPNet
@@ -121,11 +166,15 @@ type NetworkDeployer struct {
 	NodeDeploymentID map[uint32]uint64
 	NodesIPRange     map[uint32]gridtypes.IPNet
 
-	WGPort    map[uint32]int
-	Keys      map[uint32]wgtypes.Key
-	APIClient *apiClient
-	ncPool    *client.NodeClientPool
-	deployer  deployer.Deployer
+	WGPort map[uint32]int
+	Keys   map[uint32]wgtypes.Key
+	// Endpoints are the public ips of the accessible nodes
+	Endpoints map[uint32]string
+	// HiddenNodes are the nodes reached through the public node
+	HiddenNodes map[uint32]bool
+	APIClient   *apiClient
+	ncPool      *client.NodeClientPool
+	deployer    deployer.Deployer
 }
 
 func NewNetworkDeployer(ctx context.Context, d *schema.ResourceData, apiClient *apiClient) (NetworkDeployer, error) {
@@ -159,6 +208,19 @@ func NewNetworkDeployer(ctx context.Context, d *schema.ResourceData, apiClient *
 		}
 	}
 
+	endpoints := make(map[uint32]string)
+	hiddenNodes := make(map[uint32]bool)
+	for _, info := range d.Get("nodes_info").([]interface{}) {
+		info := info.(map[string]interface{})
+		node := uint32(info["node_id"].(int))
+		if info["endpoint"].(string) != "" {
+			endpoints[node] = info["endpoint"].(string)
+		}
+		if info["through_public_node"].(bool) {
+			hiddenNodes[node] = true
+		}
+	}
+
 	// external node related data
 	addWGAccess := d.Get("add_wg_access").(bool)
 
@@ -200,6 +262,7 @@ func NewNetworkDeployer(ctx context.Context, d *schema.ResourceData, apiClient *
 		Description:      d.Get("description").(string),
 		Nodes:            nodes,
 		IPRange:          ipRange,
+		NodeSubnetPrefix: d.Get("node_subnet_prefix").(int),
 		AddWGAccess:      addWGAccess,
 		AccessWGConfig:   d.Get("access_wg_config").(string),
 		ExternalIP:       externalIP,
@@ -209,6 +272,8 @@ func NewNetworkDeployer(ctx context.Context, d *schema.ResourceData, apiClient *
 		NodeDeploymentID: nodeDeploymentID,
 		Keys:             make(map[uint32]wgtypes.Key),
 		WGPort:           make(map[uint32]int),
+		Endpoints:        endpoints,
+		HiddenNodes:      hiddenNodes,
 		APIClient:        apiClient,
 		ncPool:           pool,
 		deployer:         deployer.NewDeployer(apiClient.identity, apiClient.twin_id, apiClient.grid_client, pool, true, nil, string(deploymentDataStr)),
@@ -226,6 +291,8 @@ func (k *NetworkDeployer) invalidateBrokenAttributes(sub subi.SubstrateExt) erro
 			delete(k.NodesIPRange, node)
 			delete(k.Keys, node)
 			delete(k.WGPort, node)
+			delete(k.Endpoints, node)
+			delete(k.HiddenNodes, node)
 		} else if err != nil {
 			return errors.Wrapf(err, "couldn't get node %d contract %d", node, contractID)
 		}
@@ -233,8 +300,14 @@ func (k *NetworkDeployer) invalidateBrokenAttributes(sub subi.SubstrateExt) erro
 	if k.ExternalIP != nil && !k.IPRange.Contains(k.ExternalIP.IP) {
 		k.ExternalIP = nil
 	}
+	if k.ExternalIP != nil {
+		if ones, _ := k.ExternalIP.Mask.Size(); ones != k.NodeSubnetPrefix {
+			k.ExternalIP = nil
+		}
+	}
 	for node, ip := range k.NodesIPRange {
-		if !k.IPRange.Contains(ip.IP) {
+		ones, _ := ip.Mask.Size()
+		if !k.IPRange.Contains(ip.IP) || ones != k.NodeSubnetPrefix {
 			delete(k.NodesIPRange, node)
 		}
 	}
@@ -259,9 +332,8 @@ func (k *NetworkDeployer) Validate(ctx context.Context, sub subi.SubstrateExt) e
 	if err := validateAccountMoneyForExtrinsics(sub, k.APIClient.identity); err != nil {
 		return err
 	}
-	mask := k.IPRange.Mask
-	if ones, _ := mask.Size(); ones != 16 {
-		return fmt.Errorf("subnet in iprange %s should be 16", k.IPRange.String())
+	if err := state.ValidateSubnetSizes(k.IPRange.IPNet, k.NodeSubnetPrefix); err != nil {
+		return err
 	}
 
 	return client.AreNodesUp(ctx, sub, k.Nodes, k.ncPool)
@@ -314,6 +386,11 @@ func (k *NetworkDeployer) storeState(d *schema.ResourceData, state state.StateI)
 		errors = multierror.Append(errors, err)
 	}
 
+	err = d.Set("node_subnet_prefix", k.NodeSubnetPrefix)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
 	err = d.Set("access_wg_config", k.AccessWGConfig)
 	if err != nil {
 		errors = multierror.Append(errors, err)
@@ -352,60 +429,78 @@ func (k *NetworkDeployer) storeState(d *schema.ResourceData, state state.StateI)
 		errors = multierror.Append(errors, err)
 	}
 
+	err = d.Set("nodes_info", k.nodesInfo())
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
 	return
 }
 
+// nodesInfo returns the wireguard configuration of the network nodes sorted by node id
+func (k *NetworkDeployer) nodesInfo() []interface{} {
+	nodes := make([]uint32, 0, len(k.NodeDeploymentID))
+	for node := range k.NodeDeploymentID {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	info := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		publicKey := ""
+		if key, ok := k.Keys[node]; ok {
+			publicKey = key.PublicKey().String()
+		}
+		info = append(info, map[string]interface{}{
+			"node_id":             int(node),
+			"wg_public_key":       publicKey,
+			"wg_port":             k.WGPort[node],
+			"endpoint":            k.Endpoints[node],
+			"through_public_node": k.HiddenNodes[node],
+		})
+	}
+	return info
+}
+
 func (k *NetworkDeployer) updateNetworkLocalState(state state.StateI) {
 	ns := state.GetNetworkState()
 	ns.DeleteNetwork(k.Name)
 	network := ns.GetNetwork(k.Name)
+	network.SetNodeDeploymentIDs(k.NodeDeploymentID)
 	for nodeID, subnet := range k.NodesIPRange {
 		network.SetNodeSubnet(nodeID, subnet.String())
 	}
 }
 
-func nextFreeOctet(used []byte, start *byte) error {
-	for Contains(used, *start) && *start <= 254 {
-		*start += 1
-	}
-	if *start == 255 {
-		return errors.New("couldn't find a free ip to add node")
-	}
-	return nil
-}
-
 func (k *NetworkDeployer) assignNodesIPs(nodes []uint32) error {
 	ips := make(map[uint32]gridtypes.IPNet)
-	l := len(k.IPRange.IP)
-	usedIPs := make([]byte, 0) // the third octet
+	usedSubnets := make([]net.IPNet, 0)
 	for node, ip := range k.NodesIPRange {
 		if Contains(nodes, node) {
-			usedIPs = append(usedIPs, ip.IP[l-2])
+			usedSubnets = append(usedSubnets, ip.IPNet)
 			ips[node] = ip
 		}
 	}
-	var cur byte = 2
 	if k.AddWGAccess {
 		if k.ExternalIP != nil {
-			usedIPs = append(usedIPs, k.ExternalIP.IP[l-2])
+			usedSubnets = append(usedSubnets, k.ExternalIP.IPNet)
 		} else {
-			err := nextFreeOctet(usedIPs, &cur)
+			subnet, err := state.NextFreeSubnet(k.IPRange.IPNet, k.NodeSubnetPrefix, usedSubnets)
 			if err != nil {
 				return err
 			}
-			usedIPs = append(usedIPs, cur)
-			ip := ipNet(k.IPRange.IP[l-4], k.IPRange.IP[l-3], cur, k.IPRange.IP[l-1], 24)
+			usedSubnets = append(usedSubnets, subnet)
+			ip := gridtypes.NewIPNet(subnet)
 			k.ExternalIP = &ip
 		}
 	}
 	for _, node := range nodes {
 		if _, ok := ips[node]; !ok {
-			err := nextFreeOctet(usedIPs, &cur)
+			subnet, err := state.NextFreeSubnet(k.IPRange.IPNet, k.NodeSubnetPrefix, usedSubnets)
 			if err != nil {
-				return err
+				return errors.Wrapf(err, "couldn't find a free subnet to add node %d", node)
 			}
-			usedIPs = append(usedIPs, cur)
-			ips[node] = ipNet(k.IPRange.IP[l-4], k.IPRange.IP[l-3], cur, k.IPRange.IP[l-2], 24)
+			usedSubnets = append(usedSubnets, subnet)
+			ips[node] = gridtypes.NewIPNet(subnet)
 		}
 	}
 	k.NodesIPRange = ips
@@ -456,7 +551,7 @@ func (k *NetworkDeployer) readNodesConfig(ctx context.Context, sub subi.Substrat
 		return errors.Wrap(err, "failed to print deployments")
 	}
 
-	WGAccess := false
+	peers := make([]zos.Peer, 0)
 	for node, dl := range nodeDeployments {
 		for _, wl := range dl.Workloads {
 			if wl.Type != zos.NetworkType {
@@ -474,14 +569,44 @@ func (k *NetworkDeployer) readNodesConfig(ctx context.Context, sub subi.Substrat
 				return errors.Wrap(err, "couldn't parse wg private key from workload object")
 			}
 			nodesIPRange[node] = d.Subnet
-			// this will fail when hidden node is supported
-			for _, peer := range d.Peers {
-				if peer.Endpoint == "" {
-					WGAccess = true
-				}
+			peers = append(peers, d.Peers...)
+		}
+	}
+	keyNodes := make(map[string]uint32)
+	for node, key := range keys {
+		keyNodes[key.PublicKey().String()] = node
+	}
+	endpoints := make(map[uint32]string)
+	hiddenNodes := make(map[uint32]bool)
+	WGAccess := false
+	for _, peer := range peers {
+		node, ok := keyNodes[peer.WGPublicKey]
+		if !ok {
+			// the access point is the only peer without an endpoint that's not a network node
+			if peer.Endpoint == "" {
+				WGAccess = true
 			}
+			continue
 		}
+		if peer.Endpoint == "" {
+			hiddenNodes[node] = true
+			continue
+		}
+		host, _, err := net.SplitHostPort(peer.Endpoint)
+		if err != nil {
+			log.Printf("couldn't parse node %d endpoint %s: %s", node, peer.Endpoint, err)
+			continue
+		}
+		endpoints[node] = host
 	}
+	for node := range keys {
+		// nodes with no peers (e.g. single node networks) keep the last known endpoint
+		if _, ok := endpoints[node]; !ok && !hiddenNodes[node] && k.Endpoints[node] != "" {
+			endpoints[node] = k.Endpoints[node]
+		}
+	}
+	k.Endpoints = endpoints
+	k.HiddenNodes = hiddenNodes
 	k.Keys = keys
 	k.WGPort = WGPort
 	k.NodesIPRange = nodesIPRange
@@ -507,9 +632,14 @@ func (k *NetworkDeployer) GenerateVersionlessDeployments(ctx context.Context, su
 		endpoint, err := getNodeEndpoint(ctx, cl)
 		if errors.Is(err, ErrNoAccessibleInterfaceFound) {
 			hiddenNodes = append(hiddenNodes, node)
+			k.HiddenNodes[node] = true
+			continue
 		} else if err != nil {
 			return nil, errors.Wrapf(err, "failed to get node %d endpoint", node)
-		} else if endpoint.To4() != nil {
+		}
+		delete(k.HiddenNodes, node)
+		k.Endpoints[node] = endpoint.String()
+		if endpoint.To4() != nil {
 			accessibleNodes = append(accessibleNodes, node)
 			ipv4Node = node
 			endpoints[node] = endpoint.String()
@@ -545,6 +675,7 @@ func (k *NetworkDeployer) GenerateVersionlessDeployments(ctx context.Context, su
 				return nil, errors.Wrapf(err, "failed to get node %d endpoint", k.PublicNodeID)
 			}
 			endpoints[k.PublicNodeID] = endpoint.String()
+			k.Endpoints[k.PublicNodeID] = endpoint.String()
 		}
 	}
 	all := append(hiddenNodes, accessibleNodes...)
@@ -0,0 +1,257 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
+)
+
+func resourceQSFS() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Resource for deploying a qsfs with its zdb backends, the zdbs are spread over the zdb nodes and the qsfs is deployed with the vms mounting it on the given node.",
+
+		CreateContext: ResourceFunc(resourceQSFSCreate),
+		ReadContext:   resourceQSFSReadDiags,
+		UpdateContext: ResourceFunc(resourceQSFSUpdate),
+		DeleteContext: ResourceFunc(resourceQSFSDelete),
+		CustomizeDiff: resourceQSFSCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Name of the qsfs workload, used by the vms to mount it (mounts { disk_name = <name> }) and as a prefix of the zdbs names",
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			"solution_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "QSFS",
+			},
+			"node": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Node id to deploy the qsfs and the vms on",
+			},
+			"network_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Network to use for the vms",
+			},
+			"zdb_nodes": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Nodes to spread the zdbs over, the zdbs of a removed node are replaced by new ones on the other nodes",
+			},
+			"meta_shards": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     4,
+				Description: "Number of metadata zdbs",
+			},
+			"data_shards": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Number of data zdbs, each block is split into this number of shards (expected shards)",
+			},
+			"minimal_shards": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Minimal number of data shards needed to recover a block",
+			},
+			"zdb_size": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     10,
+				Description: "Size of each zdb in GBs",
+			},
+			"zdb_password": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "Password of the zdbs",
+			},
+			"cache": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "The size of the fuse mountpoint on the node in MBs (holds qsfs local data before pushing)",
+			},
+			"max_zdb_data_dir_size": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     512,
+				Description: "Maximum size of the data dir in MiB, if this is set and the sum of the file sizes in the data dir gets higher than this value, the least used, already encoded file will be removed",
+			},
+			"encryption_key": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "64 long hex encoded encryption key (e.g. 0000000000000000000000000000000000000000000000000000000000000000), it encrypts the data and the metadata",
+			},
+			"compression_algorithm": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "snappy",
+				Description: "configuration to use for the compression stage. Currently only snappy is supported",
+			},
+			"vms": vmsSchema(),
+			"zdbs": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The zdb backends of the qsfs",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Name of the zdb workload",
+						},
+						"node": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Node of the zdb",
+						},
+						"mode": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Mode of the zdb, user for metadata and seq for data",
+						},
+						"namespace": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Namespace of the zdb",
+						},
+						"address": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Address of the zdb used by the qsfs",
+						},
+						"status": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Status of the zdb as seen by the qsfs: up, down or unknown if the metrics endpoint isn't reachable. zdbs that are down are replaced on another zdb node on the next apply, or reported as a warning if there's none",
+						},
+					},
+				},
+			},
+			"zdb_deployment_id": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Mapping from each zdb node to its deployment id",
+			},
+			"vm_deployment_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The id of the deployment holding the qsfs and the vms",
+			},
+			"ip_range": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "IP range of the node (e.g. 10.1.2.0/24)",
+			},
+			"metrics_endpoint": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "QSFS exposed metrics",
+			},
+		},
+	}
+}
+
+// downZDBs returns the zdbs that are down split by whether another zdb node can replace them
+func downZDBs(zdbs []interface{}, zdbNodesIfs []interface{}) (replaceable []string, stuck []string) {
+	zdbNodes := make([]uint32, 0, len(zdbNodesIfs))
+	for _, node := range zdbNodesIfs {
+		zdbNodes = append(zdbNodes, uint32(node.(int)))
+	}
+	for _, zdb := range zdbs {
+		mp := zdb.(map[string]interface{})
+		if mp["status"] != workloads.BackendStatusDown {
+			continue
+		}
+		if zdbReplaceable(zdbNodes, uint32(mp["node"].(int))) {
+			replaceable = append(replaceable, mp["name"].(string))
+		} else {
+			stuck = append(stuck, mp["name"].(string))
+		}
+	}
+	return
+}
+
+// resourceQSFSCustomizeDiff plans an update if any zdb is down and can be replaced on another node
+func resourceQSFSCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, i interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+	if replaceable, _ := downZDBs(d.Get("zdbs").([]interface{}), d.Get("zdb_nodes").([]interface{})); len(replaceable) != 0 {
+		return d.SetNewComputed("zdbs")
+	}
+	return nil
+}
+
+// resourceQSFSReadDiags reads the qsfs and warns about the zdbs that are down with no other zdb node to replace them
+func resourceQSFSReadDiags(ctx context.Context, d *schema.ResourceData, i interface{}) diag.Diagnostics {
+	diags := ResourceReadFunc(resourceQSFSRead)(ctx, d, i)
+	_, stuck := downZDBs(d.Get("zdbs").([]interface{}), d.Get("zdb_nodes").([]interface{}))
+	for _, name := range stuck {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("zdb %s is down", name),
+			Detail:   "there's no other zdb node to replace it on, add a node to zdb_nodes to replace it",
+		})
+	}
+	return diags
+}
+
+func resourceQSFSCreate(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	deployer, err := NewQSFSDeployer(d, apiClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployer data")
+	}
+	return &deployer, deployer.Deploy(ctx, sub)
+}
+
+func resourceQSFSUpdate(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	deployer, err := NewQSFSDeployer(d, apiClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployer data")
+	}
+	return &deployer, deployer.Deploy(ctx, sub)
+}
+
+func resourceQSFSRead(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	deployer, err := NewQSFSDeployer(d, apiClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployer data")
+	}
+	return &deployer, nil
+}
+
+func resourceQSFSDelete(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	deployer, err := NewQSFSDeployer(d, apiClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployer data")
+	}
+	return &deployer, deployer.Cancel(ctx, sub)
+}
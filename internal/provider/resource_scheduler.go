@@ -17,7 +17,7 @@ import (
 func ReourceScheduler() *schema.Resource {
 	return &schema.Resource{
 		// TODO: update descriptions
-		Description:   "Resource to dynamically assign resource requests to nodes.",
+		Description:   "Resource to dynamically assign resource requests to nodes. Requests keep their assigned nodes unless changed, only new and changed requests are scheduled.",
 		CreateContext: ResourceSchedCreate,
 		UpdateContext: ResourceSchedUpdate,
 		ReadContext:   ResourceSchedRead,
@@ -37,7 +37,7 @@ func ReourceScheduler() *schema.Resource {
 						"cru": {
 							Type:        schema.TypeInt,
 							Optional:    true,
-							Description: "Number of VCPUs",
+							Description: "Number of VCPUs, cpus are overprovisioned so it's only limited by the node cores",
 						},
 						"mru": {
 							Type:        schema.TypeInt,
@@ -64,6 +64,27 @@ func ReourceScheduler() *schema.Resource {
 							Optional:    true,
 							Description: "Pick only nodes with public config containing ipv4",
 						},
+						"public_ips": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Description: "Number of public ips needed from the node's farm",
+						},
+						"group": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Requests of the same group are spread across different nodes, farms or countries according to `spread`",
+						},
+						"spread": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     scheduler.SpreadNode,
+							Description: "Spread level of the request's group: one of node, farm or country. it must be the same for all requests of the group",
+						},
+						"pinned": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "Keep the assigned node even if the request is changed",
+						},
 						"domain": {
 							Type:        schema.TypeBool,
 							Optional:    true,
@@ -72,11 +93,42 @@ func ReourceScheduler() *schema.Resource {
 						"certified": {
 							Type:        schema.TypeBool,
 							Optional:    true,
-							Description: "Pick only certified nodes (Not implemented)",
+							Description: "Pick only certified nodes",
+						},
+						"country": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Pick only nodes in the given country",
+						},
+						"city": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Pick only nodes in the given city",
+						},
+						"dedicated": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "Pick only dedicated nodes",
+						},
+						"rented_by_me": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "Pick only nodes rented by the user's twin",
+						},
+						"min_uptime": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Description: "Pick only nodes that are up for at least the given number of seconds",
 						},
 					},
 				},
 			},
+			"policy": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     scheduler.PolicyRandom,
+				Description: "Policy of picking the nodes of new and changed requests: random, bin-pack (fill the most loaded nodes first) or spread (least loaded nodes first)",
+			},
 			"nodes": {
 				Type:        schema.TypeMap,
 				Computed:    true,
@@ -91,27 +143,32 @@ func parseAssignment(d *schema.ResourceData) map[string]uint32 {
 	assignmentIfs := d.Get("nodes").(map[string]interface{})
 	assignment := make(map[string]uint32)
 	for k, v := range assignmentIfs {
-		assignment[k] = v.(uint32)
+		assignment[k] = uint32(v.(int))
 	}
 	return assignment
 }
 
-func parseRequests(d *schema.ResourceData, assignment map[string]uint32) []scheduler.Request {
-	reqsIfs := d.Get("requests").([]interface{})
+func parseRequests(reqsIfs []interface{}) []scheduler.Request {
 	reqs := make([]scheduler.Request, 0)
 	for _, r := range reqsIfs {
 		mp := r.(map[string]interface{})
-		if _, ok := assignment[mp["name"].(string)]; ok {
-			// skip already assigned ones
-			continue
-		}
 		reqs = append(reqs, scheduler.Request{
-			Name:      mp["name"].(string),
-			Farm:      mp["farm"].(string),
-			HasIPv4:   mp["ipv4"].(bool),
-			HasDomain: mp["domain"].(bool),
-			Certified: mp["certified"].(bool),
+			Name:       mp["name"].(string),
+			Farm:       mp["farm"].(string),
+			HasIPv4:    mp["ipv4"].(bool),
+			HasDomain:  mp["domain"].(bool),
+			Certified:  mp["certified"].(bool),
+			Country:    mp["country"].(string),
+			City:       mp["city"].(string),
+			Dedicated:  mp["dedicated"].(bool),
+			RentedByMe: mp["rented_by_me"].(bool),
+			MinUptime:  uint64(mp["min_uptime"].(int)),
+			PublicIPs:  uint64(mp["public_ips"].(int)),
+			Group:      mp["group"].(string),
+			Spread:     mp["spread"].(string),
+			Pinned:     mp["pinned"].(bool),
 			Capacity: scheduler.Capacity{
+				CRU: uint64(mp["cru"].(int)),
 				MRU: uint64(mp["mru"].(int)) * uint64(gridtypes.Megabyte),
 				HRU: uint64(mp["hru"].(int)) * uint64(gridtypes.Megabyte),
 				SRU: uint64(mp["sru"].(int)) * uint64(gridtypes.Megabyte),
@@ -127,17 +184,22 @@ func schedule(ctx context.Context, d *schema.ResourceData, meta interface{}) dia
 		return diag.FromErr(fmt.Errorf("failed to cast meta into api client"))
 	}
 
-	assignment := parseAssignment(d)
-	reqs := parseRequests(d, assignment)
-	scheduler := scheduler.NewScheduler(apiClient.grid_client, uint64(apiClient.twin_id))
-	for _, r := range reqs {
-		node, err := scheduler.Schedule(&r)
-		if err != nil {
-			return diag.FromErr(errors.Wrapf(err, "couldn't schedule request %s", r.Name))
-		}
-		assignment[r.Name] = node
+	oldReqsIfs, reqsIfs := d.GetChange("requests")
+	reqs := parseRequests(reqsIfs.([]interface{}))
+	if err := validateRequests(reqs); err != nil {
+		return diag.FromErr(err)
 	}
-	err := d.Set("nodes", assignment)
+	policy, err := scheduler.NewPolicy(d.Get("policy").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	sched := scheduler.NewScheduler(apiClient.grid_client, uint64(apiClient.twin_id))
+	sched.SetPolicy(policy)
+	assignment, err := sched.Reschedule(reqs, parseRequests(oldReqsIfs.([]interface{})), parseAssignment(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = d.Set("nodes", assignment)
 	if err != nil {
 		return diag.FromErr(errors.Wrapf(err, "couldn't set nodes with %v", assignment))
 	}
@@ -145,6 +207,28 @@ func schedule(ctx context.Context, d *schema.ResourceData, meta interface{}) dia
 
 }
 
+func validateRequests(reqs []scheduler.Request) error {
+	names := make(map[string]bool)
+	spreads := make(map[string]string)
+	for _, r := range reqs {
+		if names[r.Name] {
+			return fmt.Errorf("request names must be unique: %s occurred more than once", r.Name)
+		}
+		names[r.Name] = true
+		if !Contains(scheduler.SpreadLevels, r.Spread) {
+			return fmt.Errorf("request %s spread must be one of %v, found %s", r.Name, scheduler.SpreadLevels, r.Spread)
+		}
+		if r.Group == "" {
+			continue
+		}
+		if spread, ok := spreads[r.Group]; ok && spread != r.Spread {
+			return fmt.Errorf("requests of group %s must have the same spread, found %s and %s", r.Group, spread, r.Spread)
+		}
+		spreads[r.Group] = r.Spread
+	}
+	return nil
+}
+
 func ResourceSchedRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	return diag.Diagnostics{}
 }
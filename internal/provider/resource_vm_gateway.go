@@ -0,0 +1,135 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+)
+
+func resourceVMGateway() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Resource for exposing a vm of a grid_deployment through a gateway name proxy on a node with a domain picked from the grid proxy.",
+
+		CreateContext: ResourceFunc(resourceVMGatewayCreate),
+		ReadContext:   ResourceReadFunc(resourceVMGatewayRead),
+		UpdateContext: ResourceFunc(resourceVMGatewayUpdate),
+		DeleteContext: ResourceFunc(resourceVMGatewayDelete),
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Gateway name (the fqdn will be <name>.<gateway-domain>)",
+			},
+			"solution_type": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Solution type of the gateway deployment",
+				Default:     "Gateway",
+			},
+			"node": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Node id of the vm's deployment",
+			},
+			"deployment_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Id of the grid_deployment containing the vm",
+			},
+			"vm": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Name of the vm to expose, it must have a public or yggdrasil ip",
+			},
+			"port": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Port the vm serves on",
+			},
+			"tls_passthrough": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "True to pass the tls as is to the vm.",
+			},
+			"farm": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Farm name to pick the gateway node from",
+			},
+			"country": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Country to pick the gateway node from",
+			},
+			"gateway_node": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The picked gateway node id",
+			},
+			"backend": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The backend of the gateway proxy pointing to the vm",
+			},
+			"fqdn": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The computed fully quallified domain name of the deployed workload.",
+			},
+			"node_deployment_id": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Mapping from each node to its deployment id",
+			},
+			"name_contract": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "The contract_id of a grid_name_contract reserving the name, the gateway neither creates nor cancels the name contract if set",
+			},
+			"name_contract_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The id of the name contract used by the gateway",
+			},
+		},
+	}
+}
+
+func resourceVMGatewayCreate(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	deployer, err := NewVMGatewayDeployer(d, apiClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployer data")
+	}
+	return &deployer, deployer.Deploy(ctx, sub)
+}
+
+func resourceVMGatewayUpdate(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	deployer, err := NewVMGatewayDeployer(d, apiClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployer data")
+	}
+	return &deployer, deployer.Deploy(ctx, sub)
+}
+
+func resourceVMGatewayRead(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	deployer, err := NewVMGatewayDeployer(d, apiClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployer data")
+	}
+	return &deployer, nil
+}
+
+func resourceVMGatewayDelete(ctx context.Context, sub subi.SubstrateExt, d *schema.ResourceData, apiClient *apiClient) (Marshalable, error) {
+	deployer, err := NewVMGatewayDeployer(d, apiClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployer data")
+	}
+	return &deployer, deployer.Cancel(ctx, sub)
+}
@@ -5,13 +5,15 @@ import (
 	proxyTypes "github.com/threefoldtech/grid_proxy_server/pkg/types"
 )
 
-// Capacity struct for capacity (MRU, SRU, HRU)
+// Capacity struct for capacity (CRU, MRU, SRU, HRU)
 type Capacity struct {
+	CRU uint64
 	MRU uint64
 	SRU uint64
 	HRU uint64
 }
 
+// consume reserves the request capacity, cpus are overprovisioned by zos so CRU isn't consumed
 func (c *Capacity) consume(r *Request) {
 	c.MRU -= r.Capacity.MRU
 	c.HRU -= r.Capacity.HRU
@@ -21,6 +23,8 @@ func (c *Capacity) consume(r *Request) {
 func freeCapacity(node *proxyTypes.Node) Capacity {
 	var res Capacity
 
+	// zos only limits the vcpus of a single workload to the node's cores
+	res.CRU = node.TotalResources.CRU
 	res.MRU = uint64(node.TotalResources.MRU) - uint64(node.UsedResources.MRU)
 	res.HRU = uint64(node.TotalResources.HRU) - uint64(node.UsedResources.HRU)
 	res.SRU = uint64(node.TotalResources.SRU) - uint64(node.UsedResources.SRU)
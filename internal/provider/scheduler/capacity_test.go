@@ -15,6 +15,7 @@ var (
 			MRU: 3,
 		},
 		TotalResources: proxyTypes.Capacity{
+			CRU: 4,
 			HRU: 4,
 			SRU: 5,
 			MRU: 6,
@@ -24,6 +25,7 @@ var (
 
 func TestFreeCapacity(t *testing.T) {
 	cap := freeCapacity(&node)
+	assert.Equal(t, cap.CRU, uint64(4), "cru")
 	assert.Equal(t, cap.HRU, uint64(3), "hru")
 	assert.Equal(t, cap.SRU, uint64(3), "sru")
 	assert.Equal(t, cap.MRU, uint64(3), "mru")
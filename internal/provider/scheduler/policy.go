@@ -0,0 +1,66 @@
+// Package scheduler provides a simple scheduler interface to request deployments on nodes.
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// scheduling policies names
+const (
+	PolicyRandom  = "random"
+	PolicyBinPack = "bin-pack"
+	PolicySpread  = "spread"
+)
+
+// PolicyNames are the supported scheduling policies
+var PolicyNames = []string{PolicyRandom, PolicyBinPack, PolicySpread}
+
+// Policy decides the order in which the known nodes are tried for a request
+type Policy interface {
+	// sort orders the nodes, the first one fulfilling the request is picked
+	sort(s *Scheduler, nodes []uint32)
+}
+
+// NewPolicy returns the scheduling policy with the given name
+func NewPolicy(name string) (Policy, error) {
+	switch name {
+	case PolicyRandom:
+		return randomPolicy{}, nil
+	case PolicyBinPack:
+		return binPackPolicy{}, nil
+	case PolicySpread:
+		return spreadPolicy{}, nil
+	}
+	return nil, fmt.Errorf("unknown scheduling policy %s, supported policies are %v", name, PolicyNames)
+}
+
+// randomPolicy picks a random node
+type randomPolicy struct{}
+
+func (randomPolicy) sort(s *Scheduler, nodes []uint32) {
+	rand.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+}
+
+// binPackPolicy picks the most loaded node to fill nodes before using new ones
+type binPackPolicy struct{}
+
+func (binPackPolicy) sort(s *Scheduler, nodes []uint32) {
+	randomPolicy{}.sort(s, nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := s.nodes[nodes[i]], s.nodes[nodes[j]]
+		return a.load() > b.load()
+	})
+}
+
+// spreadPolicy picks the least loaded node
+type spreadPolicy struct{}
+
+func (spreadPolicy) sort(s *Scheduler, nodes []uint32) {
+	randomPolicy{}.sort(s, nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := s.nodes[nodes[i]], s.nodes[nodes[j]]
+		return a.load() < b.load()
+	})
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	proxyTypes "github.com/threefoldtech/grid_proxy_server/pkg/types"
+)
+
+func policyProxy() *GridProxyClientMock {
+	proxy := &GridProxyClientMock{}
+	total := proxyTypes.Capacity{
+		HRU: 10,
+		SRU: 10,
+		MRU: 10,
+	}
+	proxy.AddNode(1, proxyTypes.Node{
+		NodeID:         1,
+		TotalResources: total,
+		UsedResources:  proxyTypes.Capacity{MRU: 8},
+	})
+	proxy.AddNode(2, proxyTypes.Node{
+		NodeID:         2,
+		TotalResources: total,
+		UsedResources:  proxyTypes.Capacity{SRU: 4},
+	})
+	proxy.AddNode(3, proxyTypes.Node{
+		NodeID:         3,
+		TotalResources: total,
+	})
+	return proxy
+}
+
+func TestNewPolicy(t *testing.T) {
+	for _, name := range PolicyNames {
+		_, err := NewPolicy(name)
+		assert.NoError(t, err, fmt.Sprintf("policy %s exists", name))
+	}
+	_, err := NewPolicy("first-fit")
+	assert.Error(t, err, "unknown policy")
+}
+
+func TestPolicies(t *testing.T) {
+	expected := map[string][]uint32{
+		PolicyBinPack: {1, 1, 2},
+		PolicySpread:  {3, 3, 2},
+	}
+	for name, nodes := range expected {
+		policy, err := NewPolicy(name)
+		assert.NoError(t, err, name)
+		scheduler := NewScheduler(policyProxy(), 7)
+		scheduler.SetPolicy(policy)
+		for idx, node := range nodes {
+			nodeID, err := scheduler.Schedule(&Request{
+				Name:     fmt.Sprintf("req%d", idx),
+				Capacity: Capacity{MRU: 1, SRU: 3},
+			})
+			assert.NoError(t, err, fmt.Sprintf("%s-%d", name, idx))
+			assert.Equal(t, node, nodeID, fmt.Sprintf("%s-%d", name, idx))
+		}
+	}
+}
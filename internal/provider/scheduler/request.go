@@ -2,14 +2,28 @@
 package scheduler
 
 import (
+	"reflect"
+
 	proxyTypes "github.com/threefoldtech/grid_proxy_server/pkg/types"
 )
 
 var (
 	statusUP = "up"
 	trueVal  = true
+
+	certified = "Certified"
 )
 
+// spread levels of the requests of the same group
+const (
+	SpreadNode    = "node"
+	SpreadFarm    = "farm"
+	SpreadCountry = "country"
+)
+
+// SpreadLevels are the supported spread levels
+var SpreadLevels = []string{SpreadNode, SpreadFarm, SpreadCountry}
+
 // Request struct for requesting a capacity
 type Request struct {
 	Capacity  Capacity
@@ -18,17 +32,74 @@ type Request struct {
 	HasIPv4   bool
 	HasDomain bool
 	Certified bool
+	Country   string
+	City      string
+	Dedicated bool
+	// RentedByMe picks only nodes rented by the scheduler's twin
+	RentedByMe bool
+	// MinUptime is the minimum uptime of the node in seconds
+	MinUptime uint64
+	// PublicIPs is the number of public ips needed from the node's farm
+	PublicIPs uint64
+	// Group is a set of requests that are spread across different nodes, farms or countries according to Spread
+	Group  string
+	Spread string
+	// Pinned requests keep their assigned node even if changed
+	Pinned bool
+	// Nodes restricts the scheduling to the given nodes if not empty
+	Nodes []uint32
 
 	// It is used if the Farm name is unknown ahead of time in tests and used in validation
 	farmID int
 }
 
+// allows checks whether the node is one of the request's nodes (all nodes are allowed if none is specified)
+func (r *Request) allows(node uint32) bool {
+	if len(r.Nodes) == 0 {
+		return true
+	}
+	for _, n := range r.Nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// withDefaults returns the request with the defaults of the fields missing from requests stored by older versions
+func (r Request) withDefaults() Request {
+	if r.Spread == "" {
+		r.Spread = SpreadNode
+	}
+	return r
+}
+
+// changed checks whether the request requirements differ from the old ones
+func (r *Request) changed(old *Request) bool {
+	cp := old.withDefaults()
+	cp.farmID = r.farmID
+	cp.Pinned = r.Pinned
+	return !reflect.DeepEqual(r.withDefaults(), cp)
+}
+
 func (r *Request) constructFilter(twinID uint64) (f proxyTypes.NodeFilter) {
 	f.Status = &statusUP
 	f.AvailableFor = &twinID
 	if r.Farm != "" {
 		f.FarmName = &r.Farm
 	}
+	if r.Country != "" {
+		f.Country = &r.Country
+	}
+	if r.City != "" {
+		f.City = &r.City
+	}
+	if r.Dedicated {
+		f.Dedicated = &trueVal
+	}
+	if r.RentedByMe {
+		f.RentedBy = &twinID
+	}
 	if r.Capacity.HRU != 0 {
 		f.FreeHRU = &r.Capacity.HRU
 	}
@@ -38,11 +109,18 @@ func (r *Request) constructFilter(twinID uint64) (f proxyTypes.NodeFilter) {
 	if r.Capacity.MRU != 0 {
 		f.FreeMRU = &r.Capacity.MRU
 	}
+	if r.Capacity.CRU != 0 {
+		f.TotalCRU = &r.Capacity.CRU
+	}
+	if r.PublicIPs != 0 {
+		f.FreeIPs = &r.PublicIPs
+	}
 	if r.HasDomain {
 		f.Domain = &trueVal
 	}
 	if r.HasIPv4 {
 		f.IPv4 = &trueVal
 	}
+	// the node filter of the pinned proxy client has no certification type, Certified is checked by fulfils
 	return f
 }
@@ -14,6 +14,10 @@ func TestFulfilsSuccess(t *testing.T) {
 		FarmID:       1,
 		HasIPv4:      true,
 		HasDomain:    true,
+		Country:      "Belgium",
+		City:         "Ghent",
+		Dedicated:    true,
+		Uptime:       100,
 	}
 	assert.Equal(t, nodeInfo.fulfils(&Request{
 		Capacity: Capacity{
@@ -24,6 +28,10 @@ func TestFulfilsSuccess(t *testing.T) {
 		farmID:    1,
 		HasIPv4:   true,
 		HasDomain: false,
+		Country:   "belgium",
+		City:      "Ghent",
+		Dedicated: true,
+		MinUptime: 100,
 	}), true, "fullfil-success")
 }
 
@@ -38,6 +46,7 @@ func TestFulfilsFail(t *testing.T) {
 
 	req := Request{
 		Capacity: Capacity{
+			CRU: 4,
 			MRU: 3,
 			SRU: 8,
 			HRU: 3,
@@ -47,12 +56,17 @@ func TestFulfilsFail(t *testing.T) {
 		HasDomain: false,
 	}
 	violations := map[string]func(r *Request){
-		"mru":     func(r *Request) { r.Capacity.MRU = 4 },
-		"sru":     func(r *Request) { r.Capacity.SRU = 9 },
-		"hru":     func(r *Request) { r.Capacity.HRU = 4 },
-		"farm_id": func(r *Request) { r.farmID = 2 },
-		"ipv4":    func(r *Request) { r.HasIPv4 = true },
-		"domain":  func(r *Request) { r.HasDomain = true },
+		"cru":       func(r *Request) { r.Capacity.CRU = 5 },
+		"mru":       func(r *Request) { r.Capacity.MRU = 4 },
+		"sru":       func(r *Request) { r.Capacity.SRU = 9 },
+		"hru":       func(r *Request) { r.Capacity.HRU = 4 },
+		"farm_id":   func(r *Request) { r.farmID = 2 },
+		"ipv4":      func(r *Request) { r.HasIPv4 = true },
+		"domain":    func(r *Request) { r.HasDomain = true },
+		"country":   func(r *Request) { r.Country = "Egypt" },
+		"city":      func(r *Request) { r.City = "Cairo" },
+		"dedicated": func(r *Request) { r.Dedicated = true },
+		"uptime":    func(r *Request) { r.MinUptime = 1 },
 	}
 	for key, fn := range violations {
 		cp := req
@@ -65,6 +79,7 @@ func TestConstructFilter(t *testing.T) {
 	var farm string = "freefarm"
 	r := Request{
 		Capacity: Capacity{
+			CRU: 4,
 			MRU: 1,
 			SRU: 2,
 			HRU: 3,
@@ -81,15 +96,28 @@ func TestConstructFilter(t *testing.T) {
 	assert.Equal(t, *con.FreeMRU, uint64(1), "construct-filter-mru")
 	assert.Equal(t, *con.FreeSRU, uint64(2), "construct-filter-sru")
 	assert.Equal(t, *con.FreeHRU, uint64(3), "construct-filter-hru")
+	assert.Equal(t, *con.TotalCRU, uint64(4), "construct-filter-cru")
 	assert.Empty(t, con.Country, "construct-filter-country")
 	assert.Empty(t, con.City, "construct-filter-city")
 	assert.Equal(t, *con.FarmName, "freefarm", "construct-filter-farm-name")
 	assert.Empty(t, con.FarmIDs, "construct-filter-farm-ids")
 	assert.Empty(t, con.FreeIPs, "construct-filter-free-ips")
+	r.PublicIPs = 2
+	assert.Equal(t, *r.constructFilter(1).FreeIPs, uint64(2), "construct-filter-free-ips-set")
 	assert.Equal(t, *con.IPv4, true, "construct-filter-ipv4")
 	assert.Empty(t, con.IPv6, "construct-filter-ipv6")
 	assert.Empty(t, con.Domain, "construct-filter-domain")
 	assert.Empty(t, con.Rentable, "construct-filter-rentable")
 	assert.Empty(t, con.RentedBy, "construct-filter-rented-by")
 	assert.Equal(t, *con.AvailableFor, uint64(1), "construct-filter-available-for")
+
+	r.Country = "Belgium"
+	r.City = "Ghent"
+	r.Dedicated = true
+	r.RentedByMe = true
+	con = r.constructFilter(1)
+	assert.Equal(t, *con.Country, "Belgium", "construct-filter-country-set")
+	assert.Equal(t, *con.City, "Ghent", "construct-filter-city-set")
+	assert.Equal(t, *con.Dedicated, true, "construct-filter-dedicated-set")
+	assert.Equal(t, *con.RentedBy, uint64(1), "construct-filter-rented-by-set")
 }
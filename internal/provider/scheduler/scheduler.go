@@ -3,7 +3,7 @@ package scheduler
 
 import (
 	"fmt"
-	"math/rand"
+	"strings"
 
 	"github.com/pkg/errors"
 	proxy "github.com/threefoldtech/grid_proxy_server/pkg/client"
@@ -12,19 +12,62 @@ import (
 
 // nodeInfo related to scheduling
 type nodeInfo struct {
-	FreeCapacity *Capacity
-	FarmID       int
-	HasIPv4      bool
-	HasDomain    bool
+	FreeCapacity   *Capacity
+	TotalCapacity  Capacity
+	FarmID         int
+	Country        string
+	City           string
+	Dedicated      bool
+	Uptime         uint64
+	HasIPv4        bool
+	HasDomain      bool
+	Certified      bool
+	RentedByTwinID uint64
+}
+
+// load is the highest used ratio of the node's memory and disks
+func (node *nodeInfo) load() float64 {
+	var load float64
+	for _, c := range [][2]uint64{
+		{node.FreeCapacity.MRU, node.TotalCapacity.MRU},
+		{node.FreeCapacity.SRU, node.TotalCapacity.SRU},
+		{node.FreeCapacity.HRU, node.TotalCapacity.HRU},
+	} {
+		if c[1] == 0 {
+			continue
+		}
+		if l := float64(c[1]-c[0]) / float64(c[1]); l > load {
+			load = l
+		}
+	}
+	return load
+}
+
+// spreadKey returns the node's node id, farm id or country according to the spread level
+func (node *nodeInfo) spreadKey(id uint32, spread string) string {
+	switch spread {
+	case SpreadFarm:
+		return fmt.Sprintf("farm-%d", node.FarmID)
+	case SpreadCountry:
+		return fmt.Sprintf("country-%s", node.Country)
+	default:
+		return fmt.Sprintf("node-%d", id)
+	}
 }
 
 func (node *nodeInfo) fulfils(r *Request) bool {
-	if r.Capacity.MRU > node.FreeCapacity.MRU ||
+	if r.Capacity.CRU > node.FreeCapacity.CRU ||
+		r.Capacity.MRU > node.FreeCapacity.MRU ||
 		r.Capacity.HRU > node.FreeCapacity.HRU ||
 		r.Capacity.SRU > node.FreeCapacity.SRU ||
 		(r.farmID != 0 && node.FarmID != r.farmID) ||
 		(r.HasDomain && !node.HasDomain) ||
-		(r.HasIPv4 && !node.HasIPv4) {
+		(r.HasIPv4 && !node.HasIPv4) ||
+		(r.Certified && !node.Certified) ||
+		(r.Country != "" && !strings.EqualFold(r.Country, node.Country)) ||
+		(r.City != "" && !strings.EqualFold(r.City, node.City)) ||
+		(r.Dedicated && !node.Dedicated) ||
+		r.MinUptime > node.Uptime {
 		return false
 	}
 	return true
@@ -35,7 +78,12 @@ type Scheduler struct {
 	nodes  map[uint32]nodeInfo
 	twinID uint64
 	// mapping from farm name to its id
-	farmIDS         map[string]int
+	farmIDS map[string]int
+	// mapping from farm id to its free public ips
+	farmFreeIPs map[int]uint64
+	// mapping from group name to the spread keys of its assigned requests
+	groups          map[string]map[string]bool
+	policy          Policy
 	gridProxyClient proxy.Client
 }
 
@@ -45,11 +93,19 @@ func NewScheduler(gridProxyClient proxy.Client, twinID uint64) Scheduler {
 		nodes:           map[uint32]nodeInfo{},
 		gridProxyClient: gridProxyClient,
 
-		twinID:  twinID,
-		farmIDS: make(map[string]int),
+		twinID:      twinID,
+		farmIDS:     make(map[string]int),
+		farmFreeIPs: make(map[int]uint64),
+		groups:      make(map[string]map[string]bool),
+		policy:      randomPolicy{},
 	}
 }
 
+// SetPolicy sets the policy used to pick the nodes, nodes are picked randomly by default
+func (n *Scheduler) SetPolicy(policy Policy) {
+	n.policy = policy
+}
+
 func (n *Scheduler) getFarmID(farmName string) (int, error) {
 	if id, ok := n.farmIDS[farmName]; ok {
 		return id, nil
@@ -70,20 +126,62 @@ func (n *Scheduler) getFarmID(farmName string) (int, error) {
 	return farm[0].FarmID, nil
 }
 
-func (n *Scheduler) getNode(r *Request) uint32 {
+func (n *Scheduler) getFarmFreeIPs(farmID int) (uint64, error) {
+	if ips, ok := n.farmFreeIPs[farmID]; ok {
+		return ips, nil
+	}
+	id := uint64(farmID)
+	farm, _, err := n.gridProxyClient.Farms(proxyTypes.FarmFilter{
+		FarmID: &id,
+	}, proxyTypes.Limit{
+		Size: 1,
+		Page: 1,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(farm) == 0 {
+		return 0, fmt.Errorf("farm %d not found", farmID)
+	}
+	var free uint64
+	for _, ip := range farm[0].PublicIps {
+		if ip.ContractID == 0 {
+			free++
+		}
+	}
+	n.farmFreeIPs[farmID] = free
+	return free, nil
+}
+
+func (n *Scheduler) getNode(r *Request) (uint32, error) {
 	nodes := make([]uint32, 0, len(n.nodes))
 	for node := range n.nodes {
 		nodes = append(nodes, node)
 	}
-	rand.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+	n.policy.sort(n, nodes)
 	for _, node := range nodes {
+		if !r.allows(node) {
+			continue
+		}
 		nodeInfo := n.nodes[node]
-		// TODO: later add free ips check when specifying the number of ips is supported
-		if nodeInfo.fulfils(r) {
-			return node
+		if !nodeInfo.fulfils(r) || (r.RentedByMe && nodeInfo.RentedByTwinID != n.twinID) {
+			continue
 		}
+		if r.Group != "" && n.groups[r.Group][nodeInfo.spreadKey(node, r.Spread)] {
+			continue
+		}
+		if r.PublicIPs != 0 {
+			free, err := n.getFarmFreeIPs(nodeInfo.FarmID)
+			if err != nil {
+				return 0, errors.Wrapf(err, "couldn't get farm %d free ips", nodeInfo.FarmID)
+			}
+			if free < r.PublicIPs {
+				continue
+			}
+		}
+		return node, nil
 	}
-	return 0
+	return 0, nil
 }
 
 func (n *Scheduler) addNodes(nodes []proxyTypes.Node) {
@@ -92,9 +190,21 @@ func (n *Scheduler) addNodes(nodes []proxyTypes.Node) {
 			cap := freeCapacity(&node)
 			n.nodes[uint32(node.NodeID)] = nodeInfo{
 				FreeCapacity: &cap,
-				HasIPv4:      node.PublicConfig.Ipv4 != "",
-				HasDomain:    node.PublicConfig.Domain != "",
-				FarmID:       node.FarmID,
+				TotalCapacity: Capacity{
+					CRU: node.TotalResources.CRU,
+					MRU: uint64(node.TotalResources.MRU),
+					SRU: uint64(node.TotalResources.SRU),
+					HRU: uint64(node.TotalResources.HRU),
+				},
+				HasIPv4:        node.PublicConfig.Ipv4 != "",
+				HasDomain:      node.PublicConfig.Domain != "",
+				FarmID:         node.FarmID,
+				Country:        node.Country,
+				City:           node.City,
+				Dedicated:      node.Dedicated,
+				Uptime:         uint64(node.Uptime),
+				Certified:      node.CertificationType == certified,
+				RentedByTwinID: uint64(node.RentedByTwinID),
 			}
 		}
 	}
@@ -115,7 +225,23 @@ func (n *Scheduler) Schedule(r *Request) (uint32, error) {
 		}
 		r.farmID = id
 	}
-	node := n.getNode(r)
+	node, err := n.getNode(r)
+	if err != nil {
+		return 0, err
+	}
+	if node == 0 && len(r.Nodes) != 0 {
+		// only the allowed nodes are queried instead of paging through the whole grid
+		if err := n.addRequestNodes(f, r.Nodes); err != nil {
+			return 0, err
+		}
+		node, err = n.getNode(r)
+		if err != nil {
+			return 0, err
+		}
+		if node == 0 {
+			return 0, errors.New("couldn't find a node satisfying the given requirements")
+		}
+	}
 	for node == 0 {
 		nodes, _, err := n.gridProxyClient.Nodes(f, l)
 		if err != nil {
@@ -125,7 +251,10 @@ func (n *Scheduler) Schedule(r *Request) (uint32, error) {
 			return 0, errors.New("couldn't find a node satisfying the given requirements")
 		}
 		n.addNodes(nodes)
-		node = n.getNode(r)
+		node, err = n.getNode(r)
+		if err != nil {
+			return 0, err
+		}
 		if l.Page == 1 && l.Size == 10 {
 			l.Page = 2
 		} else {
@@ -133,5 +262,99 @@ func (n *Scheduler) Schedule(r *Request) (uint32, error) {
 		}
 	}
 	n.nodes[node].FreeCapacity.consume(r)
+	if r.PublicIPs != 0 {
+		n.farmFreeIPs[n.nodes[node].FarmID] -= r.PublicIPs
+	}
+	n.addToGroup(r, node)
 	return node, nil
 }
+
+// addRequestNodes adds the given nodes that satisfy the filter and aren't known yet
+func (n *Scheduler) addRequestNodes(f proxyTypes.NodeFilter, nodes []uint32) error {
+	for _, node := range nodes {
+		if _, ok := n.nodes[node]; ok {
+			continue
+		}
+		id := uint64(node)
+		f.NodeID = &id
+		res, _, err := n.gridProxyClient.Nodes(f, proxyTypes.Limit{
+			Size: 1,
+			Page: 1,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "couldn't get node %d from the grid proxy", node)
+		}
+		n.addNodes(res)
+	}
+	return nil
+}
+
+func (n *Scheduler) addToGroup(r *Request, node uint32) {
+	if r.Group == "" {
+		return
+	}
+	if _, ok := n.groups[r.Group]; !ok {
+		n.groups[r.Group] = make(map[string]bool)
+	}
+	nodeInfo := n.nodes[node]
+	n.groups[r.Group][nodeInfo.spreadKey(node, r.Spread)] = true
+}
+
+// Assign records a previously scheduled request on the given node so that the next requests of its group are spread away from it.
+// the node capacity isn't consumed as it's already reported used by the grid proxy once deployed
+func (n *Scheduler) Assign(r *Request, node uint32) error {
+	if _, ok := n.nodes[node]; !ok {
+		info, err := n.gridProxyClient.Node(node)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't get node %d from the grid proxy", node)
+		}
+		n.addNodes([]proxyTypes.Node{{
+			NodeID:            info.NodeID,
+			FarmID:            info.FarmID,
+			Country:           info.Country,
+			City:              info.City,
+			Uptime:            info.Uptime,
+			TotalResources:    info.Capacity.Total,
+			UsedResources:     info.Capacity.Used,
+			PublicConfig:      info.PublicConfig,
+			Status:            info.Status,
+			CertificationType: info.CertificationType,
+			Dedicated:         info.Dedicated,
+			RentContractID:    info.RentContractID,
+			RentedByTwinID:    info.RentedByTwinID,
+		}})
+	}
+	n.addToGroup(r, node)
+	return nil
+}
+
+// Reschedule returns the assignment of the given requests: unchanged and pinned requests keep their assigned nodes,
+// the new and changed ones are scheduled after them and the assignments of removed requests are dropped
+func (n *Scheduler) Reschedule(reqs []Request, oldReqs []Request, assignment map[string]uint32) (map[string]uint32, error) {
+	old := make(map[string]*Request)
+	for idx := range oldReqs {
+		old[oldReqs[idx].Name] = &oldReqs[idx]
+	}
+	res := make(map[string]uint32)
+	pending := make([]Request, 0)
+	for _, r := range reqs {
+		node, ok := assignment[r.Name]
+		oldReq, existed := old[r.Name]
+		if !ok || (!r.Pinned && (!existed || r.changed(oldReq))) {
+			pending = append(pending, r)
+			continue
+		}
+		if err := n.Assign(&r, node); err != nil {
+			return nil, errors.Wrapf(err, "couldn't load request %s assignment", r.Name)
+		}
+		res[r.Name] = node
+	}
+	for _, r := range pending {
+		node, err := n.Schedule(&r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't schedule request %s", r.Name)
+		}
+		res[r.Name] = node
+	}
+	return res, nil
+}
@@ -11,6 +11,8 @@ import (
 type GridProxyClientMock struct {
 	farms []proxyTypes.Farm
 	nodes []proxyTypes.Node
+	// nodeQueries counts the calls to Nodes
+	nodeQueries int
 }
 
 func (m *GridProxyClientMock) Ping() error {
@@ -18,26 +20,41 @@ func (m *GridProxyClientMock) Ping() error {
 }
 
 func (m *GridProxyClientMock) Nodes(filter proxyTypes.NodeFilter, pagination proxyTypes.Limit) (res []proxyTypes.Node, totalCount int, err error) {
+	m.nodeQueries++
+	nodes := make([]proxyTypes.Node, 0)
+	for _, node := range m.nodes {
+		if filter.NodeID != nil && uint64(node.NodeID) != *filter.NodeID {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
 	start, end := (pagination.Page-1)*pagination.Size, pagination.Page*pagination.Size
-	if int(end) > len(m.nodes) {
-		end = uint64(len(m.nodes))
+	if int(end) > len(nodes) {
+		end = uint64(len(nodes))
 	}
 	if end <= start {
 		return make([]proxyTypes.Node, 0), 0, nil
 	}
-	res = m.nodes[start:end]
+	res = nodes[start:end]
 	return
 }
 
 func (m *GridProxyClientMock) Farms(filter proxyTypes.FarmFilter, pagination proxyTypes.Limit) (res []proxyTypes.Farm, totalCount int, err error) {
+	farms := make([]proxyTypes.Farm, 0)
+	for _, farm := range m.farms {
+		if filter.FarmID != nil && uint64(farm.FarmID) != *filter.FarmID {
+			continue
+		}
+		farms = append(farms, farm)
+	}
 	start, end := (pagination.Page-1)*pagination.Size, pagination.Page*pagination.Size
-	if int(end) > len(m.nodes) {
-		end = uint64(len(m.nodes))
+	if int(end) > len(farms) {
+		end = uint64(len(farms))
 	}
 	if end <= start {
 		return make([]proxyTypes.Farm, 0), 0, nil
 	}
-	res = m.farms[start:end]
+	res = farms[start:end]
 	return
 }
 
@@ -45,6 +62,9 @@ func (m *GridProxyClientMock) Node(nodeID uint32) (res proxyTypes.NodeWithNested
 	for _, node := range m.nodes {
 		if uint32(node.NodeID) == nodeID {
 			res = proxyTypes.NodeWithNestedCapacity{
+				NodeID:  node.NodeID,
+				FarmID:  node.FarmID,
+				Country: node.Country,
 				Capacity: proxyTypes.CapacityResult{
 					Total: node.TotalResources,
 					Used:  node.UsedResources,
@@ -348,3 +368,307 @@ func TestSchedulerSuccessAfterSuccess(t *testing.T) {
 	assert.Equal(t, nodeID, uint32(1), "the node id should be 1")
 
 }
+
+func TestSchedulerNodesAndCertified(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	capacity := proxyTypes.Capacity{
+		HRU: 5,
+		SRU: 10,
+		MRU: 15,
+	}
+	proxy.AddNode(1, proxyTypes.Node{
+		NodeID:            1,
+		TotalResources:    capacity,
+		CertificationType: "Certified",
+	})
+	proxy.AddNode(2, proxyTypes.Node{
+		NodeID:            2,
+		TotalResources:    capacity,
+		CertificationType: "Diy",
+	})
+	scheduler := NewScheduler(proxy, 1)
+	nodeID, err := scheduler.Schedule(&Request{
+		Capacity: Capacity{MRU: 1},
+		Name:     "req",
+		Nodes:    []uint32{2},
+	})
+	assert.NoError(t, err, "node 2 satisfies the request")
+	assert.Equal(t, uint32(2), nodeID, "only node 2 is allowed")
+
+	nodeID, err = scheduler.Schedule(&Request{
+		Capacity:  Capacity{MRU: 1},
+		Name:      "req",
+		Certified: true,
+	})
+	assert.NoError(t, err, "node 1 is certified")
+	assert.Equal(t, uint32(1), nodeID, "only node 1 is certified")
+
+	_, err = scheduler.Schedule(&Request{
+		Capacity:  Capacity{MRU: 1},
+		Name:      "req",
+		Certified: true,
+		Nodes:     []uint32{2},
+	})
+	assert.Error(t, err, "node 2 isn't certified")
+}
+
+func TestSchedulerQueriesOnlyRequestNodes(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	for id := 1; id <= 50; id++ {
+		proxy.AddNode(uint32(id), proxyTypes.Node{
+			NodeID:         id,
+			TotalResources: proxyTypes.Capacity{MRU: 15},
+		})
+	}
+	scheduler := NewScheduler(proxy, 1)
+	nodeID, err := scheduler.Schedule(&Request{
+		Capacity: Capacity{MRU: 1},
+		Name:     "req",
+		Nodes:    []uint32{40, 50},
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, []uint32{40, 50}, nodeID)
+	assert.Equal(t, 2, proxy.nodeQueries, "only the request nodes should be queried")
+
+	_, err = scheduler.Schedule(&Request{
+		Capacity: Capacity{MRU: 20},
+		Name:     "req",
+		Nodes:    []uint32{40, 50},
+	})
+	assert.Error(t, err, "none of the request nodes has enough memory")
+	assert.Equal(t, 2, proxy.nodeQueries, "the known nodes aren't queried again")
+}
+
+func TestSchedulerCRUAndPublicIPs(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	proxy.AddNode(1, proxyTypes.Node{
+		NodeID: 1,
+		TotalResources: proxyTypes.Capacity{
+			CRU: 2,
+			MRU: 15,
+		},
+		UsedResources: proxyTypes.Capacity{
+			CRU: 8,
+		},
+		FarmID: 1,
+	})
+	proxy.AddFarm(proxyTypes.Farm{
+		Name:   "freefarm",
+		FarmID: 1,
+		PublicIps: []proxyTypes.PublicIP{
+			{IP: "1.1.1.1/24"},
+			{IP: "1.1.1.2/24", ContractID: 5},
+			{IP: "1.1.1.3/24"},
+		},
+	})
+	scheduler := NewScheduler(proxy, 1)
+	_, err := scheduler.Schedule(&Request{
+		Capacity: Capacity{CRU: 3},
+		Name:     "req",
+	})
+	assert.Error(t, err, "a vm can't have more vcpus than the node cores")
+
+	nodeID, err := scheduler.Schedule(&Request{
+		Capacity:  Capacity{CRU: 2},
+		Name:      "req",
+		PublicIPs: 2,
+	})
+	assert.NoError(t, err, "cpus are overprovisioned and the farm has 2 free ips")
+	assert.Equal(t, uint32(1), nodeID, "the node id should be 1")
+
+	_, err = scheduler.Schedule(&Request{
+		Capacity:  Capacity{CRU: 2},
+		Name:      "req",
+		PublicIPs: 1,
+	})
+	assert.Error(t, err, "the farm free ips are all reserved")
+}
+
+func TestSchedulerSpread(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	capacity := proxyTypes.Capacity{
+		HRU: 5,
+		SRU: 10,
+		MRU: 15,
+	}
+	for i, loc := range []struct {
+		farm    int
+		country string
+	}{{1, "Egypt"}, {1, "Egypt"}, {2, "Egypt"}, {3, "Belgium"}} {
+		proxy.AddNode(uint32(i+1), proxyTypes.Node{
+			NodeID:         i + 1,
+			FarmID:         loc.farm,
+			Country:        loc.country,
+			TotalResources: capacity,
+		})
+	}
+	for _, spread := range []struct {
+		level string
+		count int
+	}{{SpreadNode, 4}, {SpreadFarm, 3}, {SpreadCountry, 2}} {
+		scheduler := NewScheduler(proxy, 1)
+		keys := make(map[string]bool)
+		for i := 0; i < spread.count; i++ {
+			nodeID, err := scheduler.Schedule(&Request{
+				Capacity: Capacity{MRU: 1},
+				Name:     fmt.Sprintf("req%d", i),
+				Group:    "replicas",
+				Spread:   spread.level,
+			})
+			assert.NoError(t, err, fmt.Sprintf("spread-%s-%d", spread.level, i))
+			info := scheduler.nodes[nodeID]
+			key := info.spreadKey(nodeID, spread.level)
+			assert.False(t, keys[key], fmt.Sprintf("spread-%s-%d should be on a different %s", spread.level, i, spread.level))
+			keys[key] = true
+		}
+		_, err := scheduler.Schedule(&Request{
+			Capacity: Capacity{MRU: 1},
+			Name:     "extra",
+			Group:    "replicas",
+			Spread:   spread.level,
+		})
+		assert.Error(t, err, fmt.Sprintf("spread-%s no %s is left", spread.level, spread.level))
+		_, err = scheduler.Schedule(&Request{
+			Capacity: Capacity{MRU: 1},
+			Name:     "other",
+			Group:    "other",
+			Spread:   spread.level,
+		})
+		assert.NoError(t, err, fmt.Sprintf("spread-%s other groups aren't affected", spread.level))
+	}
+}
+
+func TestSchedulerAssign(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	capacity := proxyTypes.Capacity{
+		HRU: 5,
+		SRU: 10,
+		MRU: 15,
+	}
+	proxy.AddNode(1, proxyTypes.Node{NodeID: 1, FarmID: 1, TotalResources: capacity})
+	proxy.AddNode(2, proxyTypes.Node{NodeID: 2, FarmID: 2, TotalResources: capacity})
+	scheduler := NewScheduler(proxy, 1)
+	err := scheduler.Assign(&Request{Name: "req1", Group: "replicas", Spread: SpreadFarm}, 1)
+	assert.NoError(t, err, "node 1 exists")
+	nodeID, err := scheduler.Schedule(&Request{
+		Capacity: Capacity{MRU: 1},
+		Name:     "req2",
+		Group:    "replicas",
+		Spread:   SpreadFarm,
+	})
+	assert.NoError(t, err, "node 2 is on another farm")
+	assert.Equal(t, uint32(2), nodeID, "farm 1 is taken by req1")
+}
+
+func TestSchedulerReschedule(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	capacity := proxyTypes.Capacity{
+		HRU: 5,
+		SRU: 10,
+		MRU: 15,
+	}
+	for i := 1; i <= 3; i++ {
+		proxy.AddNode(uint32(i), proxyTypes.Node{
+			NodeID:         i,
+			FarmID:         i,
+			TotalResources: capacity,
+		})
+	}
+	oldReqs := []Request{
+		{Name: "unchanged", Capacity: Capacity{MRU: 1}},
+		{Name: "changed", Capacity: Capacity{MRU: 1}},
+		{Name: "pinned", Capacity: Capacity{MRU: 1}, Pinned: true},
+		{Name: "removed", Capacity: Capacity{MRU: 1}},
+	}
+	// changed requests are restricted to nodes 1 and 2 so rescheduling them moves them away from node 3
+	assignment := map[string]uint32{
+		"unchanged": 3,
+		"changed":   3,
+		"pinned":    3,
+		"removed":   3,
+	}
+	reqs := []Request{
+		{Name: "unchanged", Capacity: Capacity{MRU: 1}},
+		{Name: "changed", Capacity: Capacity{MRU: 1}, Nodes: []uint32{1, 2}},
+		{Name: "pinned", Capacity: Capacity{MRU: 1}, Nodes: []uint32{1, 2}, Pinned: true},
+		{Name: "added", Capacity: Capacity{MRU: 1}, Nodes: []uint32{1}},
+	}
+	for i := 0; i < 10; i++ {
+		scheduler := NewScheduler(proxy, 1)
+		res, err := scheduler.Reschedule(reqs, oldReqs, assignment)
+		assert.NoError(t, err, "all requests are satisfiable")
+		assert.Len(t, res, 4, "removed request assignment should be dropped")
+		assert.Equal(t, uint32(3), res["unchanged"], "unchanged request should keep its node")
+		assert.Equal(t, uint32(3), res["pinned"], "pinned request should keep its node")
+		assert.Contains(t, []uint32{1, 2}, res["changed"], "changed request should be rescheduled")
+		assert.Equal(t, uint32(1), res["added"], "added request should be scheduled")
+	}
+}
+
+func TestSchedulerRescheduleSpread(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	capacity := proxyTypes.Capacity{
+		HRU: 5,
+		SRU: 10,
+		MRU: 15,
+	}
+	proxy.AddNode(1, proxyTypes.Node{NodeID: 1, FarmID: 1, TotalResources: capacity})
+	proxy.AddNode(2, proxyTypes.Node{NodeID: 2, FarmID: 1, TotalResources: capacity})
+	oldReqs := []Request{
+		{Name: "a", Capacity: Capacity{MRU: 1}, Group: "g", Spread: SpreadNode},
+	}
+	reqs := []Request{
+		{Name: "a", Capacity: Capacity{MRU: 1}, Group: "g", Spread: SpreadNode},
+		{Name: "b", Capacity: Capacity{MRU: 1}, Group: "g", Spread: SpreadNode},
+	}
+	scheduler := NewScheduler(proxy, 1)
+	res, err := scheduler.Reschedule(reqs, oldReqs, map[string]uint32{"a": 2})
+	assert.NoError(t, err, "node 1 is free for b")
+	assert.Equal(t, map[string]uint32{"a": 2, "b": 1}, res, "b should be spread away from the kept a")
+}
+
+func TestSchedulerRescheduleOldRequest(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	proxy.AddNode(1, proxyTypes.Node{NodeID: 1, FarmID: 1, TotalResources: proxyTypes.Capacity{MRU: 15}})
+	proxy.AddNode(2, proxyTypes.Node{NodeID: 2, FarmID: 1, TotalResources: proxyTypes.Capacity{MRU: 15}})
+	// requests stored before spread was added have it empty
+	oldReqs := []Request{{Name: "a", Capacity: Capacity{MRU: 1}}}
+	reqs := []Request{{Name: "a", Capacity: Capacity{MRU: 1}, Spread: SpreadNode, Nodes: []uint32{1}}}
+	scheduler := NewScheduler(proxy, 1)
+	res, err := scheduler.Reschedule(reqs, oldReqs, map[string]uint32{"a": 2})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), res["a"], "the nodes change should reschedule the request")
+
+	reqs[0].Nodes = nil
+	scheduler = NewScheduler(proxy, 1)
+	res, err = scheduler.Reschedule(reqs, oldReqs, map[string]uint32{"a": 2})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), res["a"], "the default spread isn't a change")
+}
+
+func TestSchedulerRentedByMe(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	capacity := proxyTypes.Capacity{
+		HRU: 5,
+		SRU: 10,
+		MRU: 15,
+	}
+	proxy.AddNode(1, proxyTypes.Node{NodeID: 1, TotalResources: capacity})
+	proxy.AddNode(2, proxyTypes.Node{NodeID: 2, TotalResources: capacity, RentedByTwinID: 7})
+	scheduler := NewScheduler(proxy, 7)
+	nodeID, err := scheduler.Schedule(&Request{
+		Capacity:   Capacity{MRU: 1},
+		Name:       "req",
+		RentedByMe: true,
+	})
+	assert.NoError(t, err, "node 2 is rented by twin 7")
+	assert.Equal(t, uint32(2), nodeID, "only node 2 is rented by twin 7")
+
+	scheduler = NewScheduler(proxy, 8)
+	_, err = scheduler.Schedule(&Request{
+		Capacity:   Capacity{MRU: 1},
+		Name:       "req",
+		RentedByMe: true,
+	})
+	assert.Error(t, err, "no node is rented by twin 8")
+}
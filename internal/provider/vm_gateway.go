@@ -0,0 +1,167 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/subi"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+// VMGatewayDeployer exposes a vm of a deployment through a gateway name proxy on a node picked from the grid proxy.
+// the node is kept until it becomes unavailable
+type VMGatewayDeployer struct {
+	GatewayNameDeployer
+
+	VMNode       uint32
+	DeploymentID uint64
+	VMName       string
+	Port         int
+}
+
+func NewVMGatewayDeployer(d *schema.ResourceData, apiClient *apiClient) (VMGatewayDeployer, error) {
+	nodeDeploymentIDIf := d.Get("node_deployment_id").(map[string]interface{})
+	nodeDeploymentID := make(map[uint32]uint64)
+	for node, id := range nodeDeploymentIDIf {
+		nodeInt, err := strconv.ParseUint(node, 10, 32)
+		if err != nil {
+			return VMGatewayDeployer{}, errors.Wrap(err, "couldn't parse node id")
+		}
+		nodeDeploymentID[uint32(nodeInt)] = uint64(id.(int))
+	}
+	deploymentID, err := strconv.ParseUint(d.Get("deployment_id").(string), 10, 64)
+	if err != nil {
+		return VMGatewayDeployer{}, errors.Wrapf(err, "couldn't parse deployment id %s", d.Get("deployment_id").(string))
+	}
+	backends := make([]zos.Backend, 0)
+	if backend := d.Get("backend").(string); backend != "" {
+		backends = append(backends, zos.Backend(backend))
+	}
+	oldNameContract, _ := d.GetChange("name_contract")
+	pool, gwDeployer := newGatewayDeployer(apiClient, d.Get("name").(string), d.Get("solution_type").(string))
+	return VMGatewayDeployer{
+		GatewayNameDeployer: GatewayNameDeployer{
+			Gw: workloads.GatewayNameProxy{
+				Name:           d.Get("name").(string),
+				Backends:       backends,
+				FQDN:           d.Get("fqdn").(string),
+				TLSPassthrough: d.Get("tls_passthrough").(bool),
+			},
+			ID:               d.Id(),
+			Node:             uint32(d.Get("gateway_node").(int)),
+			NodeDeploymentID: nodeDeploymentID,
+			NameContractID:   uint64(d.Get("name_contract_id").(int)),
+			NodeSelection: GatewayNodeSelection{
+				Auto:    true,
+				Farm:    d.Get("farm").(string),
+				Country: d.Get("country").(string),
+			},
+			NameContract:         uint64(d.Get("name_contract").(int)),
+			reservedNameContract: oldNameContract.(int) != 0,
+
+			APIClient: apiClient,
+			ncPool:    pool,
+			deployer:  gwDeployer,
+		},
+		VMNode:       uint32(d.Get("node").(int)),
+		DeploymentID: deploymentID,
+		VMName:       d.Get("vm").(string),
+		Port:         d.Get("port").(int),
+	}, nil
+}
+
+// vmBackend returns the gateway backend of the vm's port on its public ipv4, public ipv6 or yggdrasil ip in that order
+func vmBackend(vm *workloads.VM, port int, tlsPassthrough bool) (zos.Backend, error) {
+	for _, ip := range []string{vm.ComputedIP, vm.ComputedIP6, vm.YggIP} {
+		if ip == "" {
+			continue
+		}
+		hostPort := net.JoinHostPort(strings.Split(ip, "/")[0], strconv.Itoa(port))
+		if tlsPassthrough {
+			return zos.Backend(hostPort), nil
+		}
+		return zos.Backend(fmt.Sprintf("http://%s", hostPort)), nil
+	}
+	return "", fmt.Errorf("vm %s has neither a public nor a yggdrasil ip to be exposed through the gateway", vm.Name)
+}
+
+// resolveBackend loads the vm from its deployment to point the gateway to it
+func (k *VMGatewayDeployer) resolveBackend(ctx context.Context, sub subi.SubstrateExt) error {
+	dls, err := k.deployer.GetDeployments(ctx, sub, map[uint32]uint64{k.VMNode: k.DeploymentID})
+	if err != nil {
+		return errors.Wrapf(err, "couldn't get deployment %d on node %d", k.DeploymentID, k.VMNode)
+	}
+	dl := dls[k.VMNode]
+	wl, err := dl.Get(gridtypes.Name(k.VMName))
+	if err != nil {
+		return errors.Wrapf(err, "couldn't find vm %s in deployment %d", k.VMName, k.DeploymentID)
+	}
+	if wl.Type != zos.ZMachineType {
+		return fmt.Errorf("workload %s of deployment %d is not a vm", k.VMName, k.DeploymentID)
+	}
+	vm, err := workloads.NewVMFromWorkloads(wl.Workload, &dl)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't load vm %s", k.VMName)
+	}
+	backend, err := vmBackend(&vm, k.Port, k.Gw.TLSPassthrough)
+	if err != nil {
+		return err
+	}
+	k.Gw.Backends = []zos.Backend{backend}
+	return nil
+}
+
+func (k *VMGatewayDeployer) Deploy(ctx context.Context, sub subi.SubstrateExt) error {
+	if err := k.resolveBackend(ctx, sub); err != nil {
+		return err
+	}
+	return k.GatewayNameDeployer.Deploy(ctx, sub)
+}
+
+func (k *VMGatewayDeployer) Marshal(d *schema.ResourceData) (errors error) {
+	nodeDeploymentID := make(map[string]interface{})
+	for node, id := range k.NodeDeploymentID {
+		nodeDeploymentID[fmt.Sprintf("%d", node)] = int(id)
+	}
+	backend := ""
+	if len(k.Gw.Backends) != 0 {
+		backend = string(k.Gw.Backends[0])
+	}
+
+	d.SetId(k.ID)
+	err := d.Set("gateway_node", k.Node)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("backend", backend)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("fqdn", k.Gw.FQDN)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("node_deployment_id", nodeDeploymentID)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	err = d.Set("name_contract_id", k.NameContractID)
+	if err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
+	return
+}
@@ -0,0 +1,85 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	mock "github.com/threefoldtech/terraform-provider-grid/internal/provider/mocks"
+	"github.com/threefoldtech/terraform-provider-grid/pkg/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestVMBackend(t *testing.T) {
+	vm := workloads.VM{
+		Name:        "vm",
+		ComputedIP:  "185.206.122.33/24",
+		ComputedIP6: "2a10:b600:1::cc/64",
+		YggIP:       "300:e9c4:9048:57cf::2",
+	}
+	backend, err := vmBackend(&vm, 80, false)
+	assert.NoError(t, err)
+	assert.Equal(t, zos.Backend("http://185.206.122.33:80"), backend)
+
+	vm.ComputedIP = ""
+	backend, err = vmBackend(&vm, 443, true)
+	assert.NoError(t, err)
+	assert.Equal(t, zos.Backend("[2a10:b600:1::cc]:443"), backend)
+
+	vm.ComputedIP6 = ""
+	backend, err = vmBackend(&vm, 80, false)
+	assert.NoError(t, err)
+	assert.Equal(t, zos.Backend("http://[300:e9c4:9048:57cf::2]:80"), backend)
+
+	vm.YggIP = ""
+	_, err = vmBackend(&vm, 80, false)
+	assert.Error(t, err, "vm has no reachable ip")
+}
+
+func TestVMGatewayResolveBackend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	deployer := mock.NewMockDeployer(ctrl)
+	sub := mock.NewMockSubstrateExt(ctrl)
+	vm := workloads.VM{
+		Name:        "vm",
+		Flist:       "https://hub.grid.tf/tf-official-apps/base:latest.flist",
+		CPU:         1,
+		Memory:      1024,
+		Planetary:   true,
+		NetworkName: "network",
+		IP:          "10.1.1.2",
+	}
+	dl := workloads.NewDeployment(11)
+	dl.Workloads = vm.GenerateVMWorkload()
+	vmRes, err := json.Marshal(zos.ZMachineResult{YggIP: "300:e9c4:9048:57cf::2"})
+	assert.NoError(t, err)
+	dl.Workloads[0].Result = gridtypes.Result{
+		State: gridtypes.StateOk,
+		Data:  vmRes,
+	}
+	deployer.EXPECT().
+		GetDeployments(gomock.Any(), sub, map[uint32]uint64{5: 50}).
+		Return(map[uint32]gridtypes.Deployment{5: dl}, nil).
+		Times(2)
+
+	gw := VMGatewayDeployer{
+		GatewayNameDeployer: GatewayNameDeployer{
+			deployer: deployer,
+		},
+		VMNode:       5,
+		DeploymentID: 50,
+		VMName:       "vm",
+		Port:         8080,
+	}
+	assert.NoError(t, gw.resolveBackend(context.Background(), sub))
+	assert.Equal(t, []zos.Backend{"http://[300:e9c4:9048:57cf::2]:8080"}, gw.Gw.Backends)
+
+	gw.VMName = "missing"
+	assert.Error(t, gw.resolveBackend(context.Background(), sub), "vm doesn't exist")
+}
@@ -0,0 +1,139 @@
+package state
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// NetworkPrefix is the prefix length of network ip ranges.
+	// zos assumes /16 ranges: it derives the vms ipv6 from the last two octets of their ipv4
+	NetworkPrefix = 16
+	// NodeSubnetPrefix is the prefix length of node subnets, zos only configures /24 node subnets
+	NodeSubnetPrefix = 24
+	// DefaultNodeSubnetPrefix is the prefix length of node subnets if not specified
+	DefaultNodeSubnetPrefix = NodeSubnetPrefix
+
+	// reservedSubnets is the number of subnets at the beginning of a network ip range that are never allocated
+	reservedSubnets = 2
+	// reservedHosts is the number of host ids at the beginning of a node subnet that are never allocated (network address and gateway)
+	reservedHosts = 2
+)
+
+// hostIDs are the used host ids of a deployment inside its node subnet
+type hostIDs []uint32
+
+// UnmarshalJSON supports state files written when host ids were stored as a byte slice (the last octet of a /24 subnet)
+func (h *hostIDs) UnmarshalJSON(data []byte) error {
+	var ids []uint32
+	if err := json.Unmarshal(data, &ids); err == nil {
+		*h = ids
+		return nil
+	}
+	var octets []byte
+	if err := json.Unmarshal(data, &octets); err != nil {
+		return errors.Wrap(err, "couldn't parse used host ids")
+	}
+	ids = make([]uint32, 0, len(octets))
+	for _, octet := range octets {
+		ids = append(ids, uint32(octet))
+	}
+	*h = ids
+	return nil
+}
+
+// ValidateSubnetSizes checks that the network ip range and the node subnets have the sizes zos supports
+func ValidateSubnetSizes(ipRange net.IPNet, nodeSubnetPrefix int) error {
+	ones, bits := ipRange.Mask.Size()
+	if ipRange.IP.To4() == nil || bits != 32 {
+		return fmt.Errorf("ip range %s should be an ipv4 range", ipRange.String())
+	}
+	if ones != NetworkPrefix {
+		return fmt.Errorf("subnet in ip range %s should be %d", ipRange.String(), NetworkPrefix)
+	}
+	if nodeSubnetPrefix != NodeSubnetPrefix {
+		return fmt.Errorf("node subnet prefix %d should be %d", nodeSubnetPrefix, NodeSubnetPrefix)
+	}
+	return nil
+}
+
+// SubnetAt returns the subnet with the given index and prefix length inside the ip range
+func SubnetAt(ipRange net.IPNet, prefix int, idx uint32) (net.IPNet, error) {
+	ones, _ := ipRange.Mask.Size()
+	if prefix < ones || prefix > 32 {
+		return net.IPNet{}, fmt.Errorf("prefix %d doesn't fit in ip range %s", prefix, ipRange.String())
+	}
+	if uint64(idx) >= uint64(1)<<(prefix-ones) {
+		return net.IPNet{}, fmt.Errorf("subnet index %d is out of ip range %s", idx, ipRange.String())
+	}
+	base := binary.BigEndian.Uint32(ipRange.IP.To4().Mask(ipRange.Mask))
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, base+idx<<(32-prefix))
+	return net.IPNet{
+		IP:   ip,
+		Mask: net.CIDRMask(prefix, 32),
+	}, nil
+}
+
+// SubnetIndex returns the index of the subnet inside the ip range (e.g. 10.1.3.0/24 in 10.1.0.0/16 is 3)
+func SubnetIndex(ipRange net.IPNet, subnet net.IPNet) uint32 {
+	base := binary.BigEndian.Uint32(ipRange.IP.To4().Mask(ipRange.Mask))
+	ip := binary.BigEndian.Uint32(subnet.IP.To4().Mask(subnet.Mask))
+	ones, bits := subnet.Mask.Size()
+	return (ip - base) >> (bits - ones)
+}
+
+// NextFreeSubnet returns the first subnet of the given prefix length inside the ip range that doesn't overlap any of the used subnets
+func NextFreeSubnet(ipRange net.IPNet, prefix int, used []net.IPNet) (net.IPNet, error) {
+	ones, _ := ipRange.Mask.Size()
+	count := uint64(1) << (prefix - ones)
+	for idx := uint64(reservedSubnets); idx < count; idx++ {
+		subnet, err := SubnetAt(ipRange, prefix, uint32(idx))
+		if err != nil {
+			return net.IPNet{}, err
+		}
+		free := true
+		for _, u := range used {
+			if u.Contains(subnet.IP) || subnet.Contains(u.IP) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return subnet, nil
+		}
+	}
+	return net.IPNet{}, fmt.Errorf("couldn't find a free /%d subnet in ip range %s", prefix, ipRange.String())
+}
+
+// HostIP returns the ip with the given host id inside the subnet
+func HostIP(subnet net.IPNet, hostID uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(subnet.IP.To4().Mask(subnet.Mask))+hostID)
+	return ip
+}
+
+// HostID returns the host id of the ip inside the subnet (e.g. 10.1.3.5 in 10.1.3.0/24 is 5)
+func HostID(subnet net.IPNet, ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4()) - binary.BigEndian.Uint32(subnet.IP.To4().Mask(subnet.Mask))
+}
+
+// NextFreeHostID returns the first host id in the subnet that's not used, the network address, gateway and broadcast address are never returned
+func NextFreeHostID(subnet net.IPNet, used []uint32) (uint32, error) {
+	usedSet := make(map[uint32]struct{}, len(used))
+	for _, id := range used {
+		usedSet[id] = struct{}{}
+	}
+	ones, bits := subnet.Mask.Size()
+	count := uint64(1) << (bits - ones)
+	for id := uint64(reservedHosts); id < count-1; id++ {
+		if _, ok := usedSet[uint32(id)]; !ok {
+			return uint32(id), nil
+		}
+	}
+	return 0, fmt.Errorf("all %d ips of subnet %s are exhausted", count-reservedHosts-1, subnet.String())
+}
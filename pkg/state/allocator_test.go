@@ -0,0 +1,66 @@
+package state
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseCIDR(t *testing.T, s string) net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	assert.NoError(t, err)
+	return *n
+}
+
+func TestValidateSubnetSizes(t *testing.T) {
+	assert.NoError(t, ValidateSubnetSizes(mustParseCIDR(t, "10.1.0.0/16"), 24))
+	assert.Error(t, ValidateSubnetSizes(mustParseCIDR(t, "10.1.0.0/20"), 22))
+	assert.Error(t, ValidateSubnetSizes(mustParseCIDR(t, "10.0.0.0/8"), 24), "vms in different node subnets would get the same ipv6")
+	assert.Error(t, ValidateSubnetSizes(mustParseCIDR(t, "10.1.0.0/16"), 22))
+	assert.Error(t, ValidateSubnetSizes(mustParseCIDR(t, "10.1.0.0/16"), 26))
+	assert.Error(t, ValidateSubnetSizes(mustParseCIDR(t, "10.1.0.0/23"), 24))
+	assert.Error(t, ValidateSubnetSizes(mustParseCIDR(t, "fd00::/48"), 64))
+}
+
+func TestNextFreeSubnet(t *testing.T) {
+	ipRange := mustParseCIDR(t, "10.1.0.0/16")
+	subnet, err := NextFreeSubnet(ipRange, 24, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.2.0/24", subnet.String())
+
+	subnet, err = NextFreeSubnet(ipRange, 22, []net.IPNet{mustParseCIDR(t, "10.1.9.0/24")})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.12.0/22", subnet.String())
+	assert.Equal(t, uint32(3), SubnetIndex(ipRange, subnet))
+
+	_, err = NextFreeSubnet(mustParseCIDR(t, "10.1.0.0/22"), 24, []net.IPNet{
+		mustParseCIDR(t, "10.1.2.0/24"),
+		mustParseCIDR(t, "10.1.3.0/24"),
+	})
+	assert.Error(t, err)
+}
+
+func TestNextFreeHostID(t *testing.T) {
+	subnet := mustParseCIDR(t, "10.1.4.0/22")
+	id, err := NextFreeHostID(subnet, []uint32{2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4), id)
+	assert.Equal(t, "10.1.4.4", HostIP(subnet, id).String())
+	assert.Equal(t, uint32(300), HostID(subnet, net.ParseIP("10.1.5.44")))
+
+	used := []uint32{}
+	for i := uint32(2); i < 255; i++ {
+		used = append(used, i)
+	}
+	_, err = NextFreeHostID(mustParseCIDR(t, "10.1.4.0/24"), used)
+	assert.Error(t, err)
+}
+
+func TestLegacyHostIDs(t *testing.T) {
+	var ips deploymentIPs
+	assert.NoError(t, json.Unmarshal([]byte(`{"1": "AgME", "2": [2, 300]}`), &ips))
+	assert.Equal(t, hostIDs{2, 3, 4}, ips["1"])
+	assert.Equal(t, hostIDs{2, 300}, ips["2"])
+}
@@ -1,29 +1,36 @@
 package state
 
-type networkingState map[string]network
+type networkingState map[string]*network
 
 type network struct {
-	Subnets map[uint32]string `json:"subnets"`
-	NodeIPs NodeIPs           `json:"node_ips"`
+	Subnets           map[uint32]string `json:"subnets"`
+	NodeIPs           NodeIPs           `json:"node_ips"`
+	NodeDeploymentIDs map[uint32]uint64 `json:"node_deployment_ids"`
 }
 
 type NodeIPs map[uint32]deploymentIPs
 
-type deploymentIPs map[string][]byte
+type deploymentIPs map[string]hostIDs
 
-func NewNetwork() network {
-	return network{
-		Subnets: map[uint32]string{},
-		NodeIPs: NodeIPs{},
+func NewNetwork() *network {
+	return &network{
+		Subnets:           map[uint32]string{},
+		NodeIPs:           NodeIPs{},
+		NodeDeploymentIDs: map[uint32]uint64{},
 	}
 }
 
 func (ns networkingState) GetNetwork(networkName string) Network {
-	if _, ok := ns[networkName]; !ok {
+	if ns[networkName] == nil {
 		ns[networkName] = NewNetwork()
 	}
+	// the network is kept as a pointer so the setters of the returned network update the state
 	net := ns[networkName]
-	return &net
+	// state files written by older versions don't have this map
+	if net.NodeDeploymentIDs == nil {
+		net.NodeDeploymentIDs = map[uint32]uint64{}
+	}
+	return net
 }
 
 func (ns networkingState) DeleteNetwork(networkName string) {
@@ -42,22 +49,22 @@ func (n *network) DeleteNodeSubnet(nodeID uint32) {
 	delete(n.Subnets, nodeID)
 }
 
-func (n *network) GetNodeIPsList(nodeID uint32) []byte {
-	ips := []byte{}
+func (n *network) GetNodeIPsList(nodeID uint32) []uint32 {
+	ips := []uint32{}
 	for _, v := range n.NodeIPs[nodeID] {
 		ips = append(ips, v...)
 	}
 	return ips
 }
 
-func (n *network) GetDeploymentIPs(nodeID uint32, deploymentID string) []byte {
+func (n *network) GetDeploymentIPs(nodeID uint32, deploymentID string) []uint32 {
 	if n.NodeIPs[nodeID] == nil {
-		return []byte{}
+		return []uint32{}
 	}
 	return n.NodeIPs[nodeID][deploymentID]
 }
 
-func (n *network) SetDeploymentIPs(nodeID uint32, deploymentID string, ips []byte) {
+func (n *network) SetDeploymentIPs(nodeID uint32, deploymentID string, ips []uint32) {
 	if n.NodeIPs[nodeID] == nil {
 		n.NodeIPs[nodeID] = deploymentIPs{}
 	}
@@ -70,3 +77,20 @@ func (n *network) DeleteDeployment(nodeID uint32, deploymentID string) {
 	}
 	delete(n.NodeIPs[nodeID], deploymentID)
 }
+
+func (n *network) GetNodeDeploymentIDs() map[uint32]uint64 {
+	ids := make(map[uint32]uint64, len(n.NodeDeploymentIDs))
+	for node, id := range n.NodeDeploymentIDs {
+		ids[node] = id
+	}
+	return ids
+}
+
+func (n *network) SetNodeDeploymentIDs(ids map[uint32]uint64) {
+	for node := range n.NodeDeploymentIDs {
+		delete(n.NodeDeploymentIDs, node)
+	}
+	for node, id := range ids {
+		n.NodeDeploymentIDs[node] = id
+	}
+}
@@ -0,0 +1,28 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkSetters(t *testing.T) {
+	st := NewState()
+	ns := st.GetNetworkState()
+	network := ns.GetNetwork("net")
+	network.SetNodeSubnet(13, "10.1.2.0/24")
+	network.SetNodeDeploymentIDs(map[uint32]uint64{13: 130})
+
+	network = ns.GetNetwork("net")
+	assert.Equal(t, "10.1.2.0/24", network.GetNodeSubnet(13))
+	assert.Equal(t, map[uint32]uint64{13: 130}, network.GetNodeDeploymentIDs())
+
+	// the values survive saving and loading the state
+	bt, err := st.Marshal()
+	assert.NoError(t, err)
+	var loaded state
+	assert.NoError(t, loaded.Unmarshal(bt))
+	network = loaded.GetNetworkState().GetNetwork("net")
+	assert.Equal(t, "10.1.2.0/24", network.GetNodeSubnet(13))
+	assert.Equal(t, map[uint32]uint64{13: 130}, network.GetNodeDeploymentIDs())
+}
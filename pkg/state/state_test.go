@@ -17,7 +17,7 @@ func TestState(t *testing.T) {
 	network := ns.GetNetwork("abc")
 	network.SetNodeSubnet(32, "10.1.1.0/24")
 	network.SetNodeSubnet(15, "10.1.1.0/24")
-	network.SetDeploymentIPs(32, "12345", []byte{1, 2, 3})
+	network.SetDeploymentIPs(32, "12345", []uint32{1, 2, 3})
 	network.DeleteDeployment(32, "12345")
 	network.DeleteNodeSubnet(32)
 	err = f.Save()
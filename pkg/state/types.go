@@ -44,14 +44,18 @@ type Network interface {
 	SetNodeSubnet(nodeID uint32, subnet string)
 	// DeleteNodeSubnet deletes node's subnet from network local state
 	DeleteNodeSubnet(nodeID uint32)
-	// GetNodeIPs retrieves all node's used ips
-	GetNodeIPsList(nodeID uint32) []byte
-	// GetDeploymentIPs retrieves deployment's used ips
-	GetDeploymentIPs(nodeID uint32, deploymentID string) []byte
-	// SetDeploymentIPs sets deployment's used ips
-	SetDeploymentIPs(nodeID uint32, deploymentID string, ips []byte)
+	// GetNodeIPs retrieves all node's used ips as host ids inside the node subnet
+	GetNodeIPsList(nodeID uint32) []uint32
+	// GetDeploymentIPs retrieves deployment's used ips as host ids inside the node subnet
+	GetDeploymentIPs(nodeID uint32, deploymentID string) []uint32
+	// SetDeploymentIPs sets deployment's used ips as host ids inside the node subnet
+	SetDeploymentIPs(nodeID uint32, deploymentID string, ips []uint32)
 	// RemoveDeployment deletes deployment entry
 	DeleteDeployment(nodeID uint32, deploymentID string)
+	// GetNodeDeploymentIDs retrieves network's deployment id on each node
+	GetNodeDeploymentIDs() map[uint32]uint64
+	// SetNodeDeploymentIDs sets network's deployment id on each node
+	SetNodeDeploymentIDs(ids map[uint32]uint64)
 }
 
 func NewLocalStateDB(t DBType) (DB, error) {
@@ -12,6 +12,8 @@ type Contract interface {
 	IsCreated() bool
 	TwinID() uint32
 	PublicIPCount() uint32
+	// Name is the name reserved by a name contract
+	Name() string
 }
 
 type DevContract struct {
@@ -33,6 +35,10 @@ func (c *DevContract) PublicIPCount() uint32 {
 	return uint32(c.Contract.ContractType.NodeContract.PublicIPsCount)
 }
 
+func (c *DevContract) Name() string {
+	return string(c.Contract.ContractType.NameContract.Name)
+}
+
 type QAContract struct {
 	*subqa.Contract
 }
@@ -52,6 +58,10 @@ func (c *QAContract) PublicIPCount() uint32 {
 	return uint32(c.Contract.ContractType.NodeContract.PublicIPsCount)
 }
 
+func (c *QAContract) Name() string {
+	return string(c.Contract.ContractType.NameContract.Name)
+}
+
 type TestContract struct {
 	*subtest.Contract
 }
@@ -71,6 +81,10 @@ func (c *TestContract) PublicIPCount() uint32 {
 	return uint32(c.Contract.ContractType.NodeContract.PublicIPsCount)
 }
 
+func (c *TestContract) Name() string {
+	return string(c.Contract.ContractType.NameContract.Name)
+}
+
 type MainContract struct {
 	*submain.Contract
 }
@@ -89,3 +103,7 @@ func (c *MainContract) TwinID() uint32 {
 func (c *MainContract) PublicIPCount() uint32 {
 	return uint32(c.Contract.ContractType.NodeContract.PublicIPsCount)
 }
+
+func (c *MainContract) Name() string {
+	return string(c.Contract.ContractType.NameContract.Name)
+}
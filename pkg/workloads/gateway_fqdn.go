@@ -2,11 +2,19 @@
 package workloads
 
 import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/threefoldtech/zos/pkg/gridtypes"
 	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
 )
 
+var gatewayNameRegex = regexp.MustCompile(`^[a-zA-Z0-9-.]+$`)
+
 // GatewayFQDNProxy for gateway FQDN proxy
 type GatewayFQDNProxy struct {
 	// Name the fully qualified domain name to use (cannot be present with Name)
@@ -52,3 +60,55 @@ func (g *GatewayFQDNProxy) ZosWorkload() gridtypes.Workload {
 		}),
 	}
 }
+
+// Validate validates the gateway fqdn and backends
+func (g *GatewayFQDNProxy) Validate() error {
+	if !gatewayNameRegex.MatchString(g.FQDN) {
+		return fmt.Errorf("invalid fqdn %s, it can only contain letters, digits, dashes and dots", g.FQDN)
+	}
+	if strings.HasSuffix(g.FQDN, ".") {
+		return fmt.Errorf("fqdn %s can't end with a dot", g.FQDN)
+	}
+	return ValidateBackends(g.Backends, g.TLSPassthrough)
+}
+
+// ValidateBackends validates the gateway backends are in the format ip:port with tls passthrough, and http://ip[:port] otherwise
+func ValidateBackends(backends []zos.Backend, tlsPassthrough bool) error {
+	if len(backends) == 0 {
+		return errors.New("backends list can not be empty")
+	}
+	for _, backend := range backends {
+		if err := validateBackend(string(backend), tlsPassthrough); err != nil {
+			return errors.Wrapf(err, "invalid backend %s", backend)
+		}
+	}
+	return nil
+}
+
+func validateBackend(backend string, tlsPassthrough bool) error {
+	var host string
+	if tlsPassthrough {
+		h, port, err := net.SplitHostPort(backend)
+		if err != nil {
+			return errors.Wrap(err, "backends must be in the format ip:port with tls passthrough")
+		}
+		if port == "" {
+			return errors.New("missing port, backends must be in the format ip:port with tls passthrough")
+		}
+		host = h
+	} else {
+		u, err := url.Parse(backend)
+		if err != nil {
+			return errors.Wrap(err, "backends must be in the format http://ip[:port] without tls passthrough")
+		}
+		if u.Scheme != "http" {
+			return errors.New("backends must be in the format http://ip[:port] without tls passthrough, the gateway terminates tls")
+		}
+		host = u.Hostname()
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() {
+		return fmt.Errorf("%s is not a valid non loopback ip", host)
+	}
+	return nil
+}
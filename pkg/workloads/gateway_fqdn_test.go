@@ -0,0 +1,53 @@
+// Package workloads includes workloads types (vm, zdb, qsfs, public IP, gateway name, gateway fqdn, disk)
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+func TestValidateBackends(t *testing.T) {
+	valid := map[bool][]zos.Backend{
+		false: {"http://1.1.1.1", "http://1.1.1.1:8080", "http://[2a02:1802:5e::1]:8080", "http://[300:e9c4:9048:57cf::2]:80"},
+		true:  {"1.1.1.1:443", "[2a02:1802:5e::1]:443", "[300:e9c4:9048:57cf::2]:443"},
+	}
+	invalid := map[bool][]zos.Backend{
+		false: {"https://1.1.1.1", "1.1.1.1:80", "http://example.com", "http://127.0.0.1:80", "http://300.1.1.1"},
+		true:  {"http://1.1.1.1:443", "1.1.1.1", "[2a02:1802:5e::1]", "127.0.0.1:443", "example.com:443"},
+	}
+	for tls, backends := range valid {
+		for _, backend := range backends {
+			assert.NoError(t, ValidateBackends([]zos.Backend{backend}, tls), "tls passthrough: %t, backend: %s", tls, backend)
+		}
+	}
+	for tls, backends := range invalid {
+		for _, backend := range backends {
+			assert.Error(t, ValidateBackends([]zos.Backend{backend}, tls), "tls passthrough: %t, backend: %s", tls, backend)
+		}
+	}
+	assert.Error(t, ValidateBackends(nil, false), "empty backends")
+}
+
+func TestGatewayValidate(t *testing.T) {
+	fqdn := GatewayFQDNProxy{
+		Name:     "name",
+		FQDN:     "name.com",
+		Backends: []zos.Backend{"http://1.1.1.1"},
+	}
+	assert.NoError(t, fqdn.Validate())
+	fqdn.FQDN = "name.com."
+	assert.Error(t, fqdn.Validate(), "fqdn ending with a dot")
+
+	name := GatewayNameProxy{
+		Name:     "name",
+		Backends: []zos.Backend{"http://1.1.1.1"},
+	}
+	assert.NoError(t, name.Validate())
+	name.Name = "name_1"
+	assert.Error(t, name.Validate(), "invalid gateway name")
+	name.Name = "name"
+	name.TLSPassthrough = true
+	assert.Error(t, name.Validate(), "http backend with tls passthrough")
+}
@@ -3,6 +3,7 @@ package workloads
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/pkg/errors"
 	"github.com/threefoldtech/zos/pkg/gridtypes"
@@ -59,3 +60,11 @@ func (g *GatewayNameProxy) ZosWorkload() gridtypes.Workload {
 		}),
 	}
 }
+
+// Validate validates the gateway name and backends
+func (g *GatewayNameProxy) Validate() error {
+	if !gatewayNameRegex.MatchString(g.Name) {
+		return fmt.Errorf("invalid gateway name %s, it can only contain letters, digits, dashes and dots", g.Name)
+	}
+	return ValidateBackends(g.Backends, g.TLSPassthrough)
+}
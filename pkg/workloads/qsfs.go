@@ -2,8 +2,15 @@
 package workloads
 
 import (
+	"bufio"
 	"encoding/hex"
-	"log"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/threefoldtech/zos/pkg/gridtypes"
@@ -27,6 +34,8 @@ type QSFS struct {
 	Groups               Groups
 
 	MetricsEndpoint string
+	// BackendsStatus is the status of the backends as reported by the metrics endpoint
+	BackendsStatus BackendsStatus
 }
 
 // Metadata for QSFS
@@ -52,6 +61,28 @@ type Groups []Group
 // Backends is a list of backends
 type Backends []Backend
 
+// backend statuses
+const (
+	BackendStatusUp      = "up"
+	BackendStatusDown    = "down"
+	BackendStatusUnknown = "unknown"
+)
+
+var (
+	backendStatusMetric = regexp.MustCompile(`^\w*backend_status\{(.*)\}\s+(\S+)`)
+	metricLabel         = regexp.MustCompile(`(\w+)="((?:[^"\\]|\\.)*)"`)
+)
+
+// BackendStatus is whether a backend is reachable by the qsfs
+type BackendStatus struct {
+	Address   string
+	Namespace string
+	Up        bool
+}
+
+// BackendsStatus is a list of backends statuses
+type BackendsStatus []BackendStatus
+
 func (g *Group) zosGroup() zos.ZdbGroup {
 	z := zos.ZdbGroup{
 		Backends: make([]zos.ZdbBackend, 0),
@@ -186,40 +217,11 @@ func NewQSFSFromSchema(qsfs map[string]interface{}) QSFS {
 
 // NewQSFSFromWorkload generates a new QSFS from a workload
 func NewQSFSFromWorkload(wl *gridtypes.Workload) (QSFS, error) {
-
-	var data *zos.QuantumSafeFS
-	wd, err := wl.WorkloadData()
-	if err != nil {
+	var q QSFS
+	if err := q.UpdateFromWorkload(wl); err != nil {
 		return QSFS{}, err
 	}
-	var res zos.QuatumSafeFSResult
-	if err := wl.Result.Unmarshal(&res); err != nil {
-		return QSFS{}, err
-	}
-	log.Printf("wl.Result.unm: %s %s\n", res.MetricsEndpoint, res.Path)
-	data = wd.(*zos.QuantumSafeFS)
-	return QSFS{
-		Name:                 string(wl.Name),
-		Description:          string(wl.Description),
-		Cache:                int(data.Cache) / int(gridtypes.Megabyte),
-		MinimalShards:        data.Config.MinimalShards,
-		ExpectedShards:       data.Config.ExpectedShards,
-		RedundantGroups:      data.Config.RedundantGroups,
-		RedundantNodes:       data.Config.RedundantNodes,
-		MaxZDBDataDirSize:    data.Config.MaxZDBDataDirSize,
-		EncryptionAlgorithm:  string(data.Config.Encryption.Algorithm),
-		EncryptionKey:        hex.EncodeToString(data.Config.Encryption.Key),
-		CompressionAlgorithm: data.Config.Compression.Algorithm,
-		Metadata: Metadata{
-			Type:                data.Config.Meta.Type,
-			Prefix:              data.Config.Meta.Config.Prefix,
-			EncryptionAlgorithm: string(data.Config.Meta.Config.Encryption.Algorithm),
-			EncryptionKey:       hex.EncodeToString(data.Config.Meta.Config.Encryption.Key),
-			Backends:            BackendsFromZos(data.Config.Meta.Config.Backends),
-		},
-		Groups:          GroupsFromZos(data.Config.Groups),
-		MetricsEndpoint: res.MetricsEndpoint,
-	}, nil
+	return q, nil
 }
 
 func getBackends(backendsIf []interface{}) []Backend {
@@ -289,22 +291,127 @@ func (q *QSFS) QSFS() string {
 	return q.Name
 }
 
-// UpdateFromWorkload updates a QSFS from a workload
-// TODO: no updates, should construct itself from the workload
+// UpdateFromWorkload updates a QSFS from a workload, the backends are the ones the qsfs currently uses
+// so backends swapped by a workload update are reflected without recreating the qsfs
 func (q *QSFS) UpdateFromWorkload(wl *gridtypes.Workload) error {
 	if wl == nil {
 		q.MetricsEndpoint = ""
 		return nil
 	}
+	wd, err := wl.WorkloadData()
+	if err != nil {
+		return err
+	}
+	data, ok := wd.(*zos.QuantumSafeFS)
+	if !ok {
+		return fmt.Errorf("workload %s isn't a qsfs", wl.Name)
+	}
 	var res zos.QuatumSafeFSResult
 	if err := wl.Result.Unmarshal(&res); err != nil {
 		return errors.Wrap(err, "error unmarshalling json")
-
 	}
+	q.Name = string(wl.Name)
+	q.Description = wl.Description
+	q.Cache = int(data.Cache) / int(gridtypes.Megabyte)
+	q.MinimalShards = data.Config.MinimalShards
+	q.ExpectedShards = data.Config.ExpectedShards
+	q.RedundantGroups = data.Config.RedundantGroups
+	q.RedundantNodes = data.Config.RedundantNodes
+	q.MaxZDBDataDirSize = data.Config.MaxZDBDataDirSize
+	q.EncryptionAlgorithm = string(data.Config.Encryption.Algorithm)
+	q.EncryptionKey = hex.EncodeToString(data.Config.Encryption.Key)
+	q.CompressionAlgorithm = data.Config.Compression.Algorithm
+	q.Metadata = Metadata{
+		Type:                data.Config.Meta.Type,
+		Prefix:              data.Config.Meta.Config.Prefix,
+		EncryptionAlgorithm: string(data.Config.Meta.Config.Encryption.Algorithm),
+		EncryptionKey:       hex.EncodeToString(data.Config.Meta.Config.Encryption.Key),
+		Backends:            BackendsFromZos(data.Config.Meta.Config.Backends),
+	}
+	q.Groups = GroupsFromZos(data.Config.Groups)
 	q.MetricsEndpoint = res.MetricsEndpoint
 	return nil
 }
 
+// Status returns the status of the backend, it's unknown if the metrics don't report it
+func (s BackendsStatus) Status(b Backend) string {
+	for _, status := range s {
+		if status.Address != b.Address || status.Namespace != b.Namespace {
+			continue
+		}
+		if status.Up {
+			return BackendStatusUp
+		}
+		return BackendStatusDown
+	}
+	return BackendStatusUnknown
+}
+
+// ParseBackendsStatus parses the backends status from the qsfs prometheus metrics.
+// zstor reports each backend through a *backend_status gauge labeled with its address and namespace, it's up if positive
+func ParseBackendsStatus(metrics io.Reader) (BackendsStatus, error) {
+	statuses := make(BackendsStatus, 0)
+	scanner := bufio.NewScanner(metrics)
+	for scanner.Scan() {
+		match := backendStatusMetric.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid backend status value %s", match[2])
+		}
+		status := BackendStatus{Up: value > 0}
+		for _, label := range metricLabel.FindAllStringSubmatch(match[1], -1) {
+			labelValue, err := strconv.Unquote(fmt.Sprintf(`"%s"`, label[2]))
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid label %s", label[1])
+			}
+			switch label[1] {
+			case "address":
+				status.Address = labelValue
+			case "namespace":
+				status.Namespace = labelValue
+			}
+		}
+		if status.Address != "" {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, scanner.Err()
+}
+
+// GetBackendsStatus gets the backends status from the qsfs metrics endpoint
+func GetBackendsStatus(endpoint string) (BackendsStatus, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	response, err := client.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics endpoint responded with %s", response.Status)
+	}
+	return ParseBackendsStatus(response.Body)
+}
+
+// backendsStatusListify lists the status of the metadata backends then the groups backends
+func (q *QSFS) backendsStatusListify() []interface{} {
+	res := make([]interface{}, 0)
+	backends := append(Backends{}, q.Metadata.Backends...)
+	for _, g := range q.Groups {
+		backends = append(backends, g.Backends...)
+	}
+	for _, b := range backends {
+		res = append(res, map[string]interface{}{
+			"address":   b.Address,
+			"namespace": b.Namespace,
+			"status":    q.BackendsStatus.Status(b),
+		})
+	}
+	return res
+}
+
 // Dictify converts a QSFS data to a map
 func (q *QSFS) Dictify() map[string]interface{} {
 	res := make(map[string]interface{})
@@ -322,5 +429,6 @@ func (q *QSFS) Dictify() map[string]interface{} {
 	res["metrics_endpoint"] = q.MetricsEndpoint
 	res["metadata"] = []interface{}{q.Metadata.Dictify()}
 	res["groups"] = q.Groups.Listify()
+	res["backends_status"] = q.backendsStatusListify()
 	return res
 }
@@ -0,0 +1,129 @@
+// Package workloads includes workloads types (vm, zdb, qsfs, public IP, gateway name, gateway fqdn, disk)
+package workloads
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
+)
+
+const qsfsMetrics = `# HELP backend_status Status of the backend
+# TYPE backend_status gauge
+data_backend_status{address="[300::1]:9900",namespace="ns1"} 1
+data_backend_status{address="[300::1]:9900",namespace="ns2"} 0
+meta_backend_status{address="[300::2]:9900",namespace="ns3",backend_type="meta"} 1
+zstor_data_size{address="[300::1]:9900",namespace="ns1"} 1024
+`
+
+func qsfsObj() QSFS {
+	return QSFS{
+		Name:                 "q",
+		Description:          "des",
+		Cache:                1024,
+		MinimalShards:        1,
+		ExpectedShards:       2,
+		MaxZDBDataDirSize:    512,
+		EncryptionAlgorithm:  "AES",
+		EncryptionKey:        strings.Repeat("0", 64),
+		CompressionAlgorithm: "snappy",
+		Metadata: Metadata{
+			Type:                "zdb",
+			Prefix:              "q",
+			EncryptionAlgorithm: "AES",
+			EncryptionKey:       strings.Repeat("0", 64),
+			Backends:            Backends{{Address: "[300::2]:9900", Namespace: "ns3", Password: "pass"}},
+		},
+		Groups: Groups{{Backends: Backends{
+			{Address: "[300::1]:9900", Namespace: "ns1", Password: "pass"},
+			{Address: "[300::1]:9900", Namespace: "ns2", Password: "pass"},
+		}}},
+		MetricsEndpoint: "http://[300::3]:9100/metrics",
+	}
+}
+
+func qsfsWl(t *testing.T, q QSFS) gridtypes.Workload {
+	wl, err := q.ZosWorkload()
+	assert.NoError(t, err)
+	res, err := json.Marshal(zos.QuatumSafeFSResult{MetricsEndpoint: q.MetricsEndpoint})
+	assert.NoError(t, err)
+	wl.Result = gridtypes.Result{State: gridtypes.StateOk, Data: res}
+	return wl
+}
+
+func TestParseBackendsStatus(t *testing.T) {
+	statuses, err := ParseBackendsStatus(strings.NewReader(qsfsMetrics))
+	assert.NoError(t, err)
+	assert.Equal(t, BackendsStatus{
+		{Address: "[300::1]:9900", Namespace: "ns1", Up: true},
+		{Address: "[300::1]:9900", Namespace: "ns2", Up: false},
+		{Address: "[300::2]:9900", Namespace: "ns3", Up: true},
+	}, statuses)
+
+	_, err = ParseBackendsStatus(strings.NewReader(`backend_status{address="[300::1]:9900"} up`))
+	assert.Error(t, err)
+}
+
+func TestBackendsStatus(t *testing.T) {
+	statuses := BackendsStatus{
+		{Address: "[300::1]:9900", Namespace: "ns1", Up: true},
+		{Address: "[300::1]:9900", Namespace: "ns2", Up: false},
+	}
+	assert.Equal(t, BackendStatusUp, statuses.Status(Backend{Address: "[300::1]:9900", Namespace: "ns1"}))
+	assert.Equal(t, BackendStatusDown, statuses.Status(Backend{Address: "[300::1]:9900", Namespace: "ns2"}))
+	assert.Equal(t, BackendStatusUnknown, statuses.Status(Backend{Address: "[300::1]:9900", Namespace: "ns3"}))
+}
+
+func TestGetBackendsStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, qsfsMetrics)
+	}))
+	defer server.Close()
+	statuses, err := GetBackendsStatus(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 3)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	_, err = GetBackendsStatus(failing.URL)
+	assert.Error(t, err)
+}
+
+func TestNewQSFSFromWorkload(t *testing.T) {
+	q := qsfsObj()
+	wl := qsfsWl(t, q)
+	loaded, err := NewQSFSFromWorkload(&wl)
+	assert.NoError(t, err)
+	assert.Equal(t, q, loaded)
+}
+
+func TestQSFSUpdateFromWorkload(t *testing.T) {
+	q := qsfsObj()
+	swapped := qsfsObj()
+	swapped.Groups[0].Backends[1] = Backend{Address: "[300::4]:9900", Namespace: "ns4", Password: "pass"}
+	wl := qsfsWl(t, swapped)
+	assert.NoError(t, q.UpdateFromWorkload(&wl))
+	assert.Equal(t, swapped.Groups, q.Groups)
+	assert.Equal(t, swapped.Metadata, q.Metadata)
+
+	assert.NoError(t, q.UpdateFromWorkload(nil))
+	assert.Empty(t, q.MetricsEndpoint)
+}
+
+func TestQSFSDictifyBackendsStatus(t *testing.T) {
+	q := qsfsObj()
+	q.BackendsStatus = BackendsStatus{{Address: "[300::1]:9900", Namespace: "ns2", Up: false}}
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"address": "[300::2]:9900", "namespace": "ns3", "status": BackendStatusUnknown},
+		map[string]interface{}{"address": "[300::1]:9900", "namespace": "ns1", "status": BackendStatusUnknown},
+		map[string]interface{}{"address": "[300::1]:9900", "namespace": "ns2", "status": BackendStatusDown},
+	}, q.Dictify()["backends_status"])
+}